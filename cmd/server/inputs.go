@@ -0,0 +1,478 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sync"
+	"time"
+
+	"log-sentry/internal/analyzer"
+	"log-sentry/internal/anomaly"
+	"log-sentry/internal/collector"
+	"log-sentry/internal/config"
+	"log-sentry/internal/discovery"
+	"log-sentry/internal/ingest"
+	"log-sentry/internal/parser"
+	"log-sentry/internal/worker"
+)
+
+// buildInputs translates the configuration and the auto-discovered services
+// into the set of inputs the ingest manager should be running.
+func buildInputs(cfg *config.Config, services []discovery.DetectedService, wp *worker.Pool, scans *anomaly.PortScanDetector, coll *collector.LogCollector) ([]ingest.Input, error) {
+	var inputs []ingest.Input
+
+	custom, err := customParsers(cfg.Parsers)
+	if err != nil {
+		return nil, err
+	}
+
+	// Declared Inputs (config file)
+	for i, in := range cfg.Inputs {
+		var input ingest.Input
+		switch in.Type {
+		case config.InputSSH:
+			input = sshInput(in.Service, in.Path, wp.Analyzer, coll)
+		case config.InputError:
+			input = errorInput(in.Service, in.Path, coll)
+		case config.InputApp:
+			input = appInput(in.Service, in.Path, coll)
+		case config.InputAudit:
+			input = auditInput(in.Service, in.Path, coll)
+		case config.InputFirewall:
+			input = firewallInput(in.Service, in.Path, scans, coll)
+		case config.InputMail:
+			input = mailInput(in.Service, in.Path, wp.AnomalyDetector, coll)
+		default:
+			newParser, format, err := inputParser(in, custom)
+			if err != nil {
+				return nil, fmt.Errorf("inputs[%d]: %v", i, err)
+			}
+			input = webInput(in.Service, in.Path, in.Parser, newParser, wp, coll)
+			if format != "" {
+				input.Key += "|" + format
+			}
+			if in.VhostPattern != "" {
+				input.Key += "|" + in.VhostPattern
+				input.VhostPattern = regexp.MustCompile(in.VhostPattern) // Validated by config
+			}
+		}
+		if ml := in.Multiline; ml != nil {
+			input.Key += fmt.Sprintf("|%+v", *ml)
+			input.Multiline = &ingest.Multiline{
+				Start:    compileOptional(ml.StartPattern), // Validated by config
+				Continue: compileOptional(ml.ContinuePattern),
+				MaxLines: ml.MaxLines,
+				Timeout:  ml.FlushTimeout,
+			}
+		}
+		if in.Envelope != "" {
+			input.Key += "|" + in.Envelope
+			input.Envelope = envelope(in.Envelope)
+		}
+		inputs = append(inputs, input)
+	}
+
+	// Auto-Discovered Services. The process name only hints at the format
+	// (nginx may well log JSON), so it is detected from the file itself;
+	// services without a parser of their own are skipped.
+	for _, svc := range services {
+		if _, err := parser.New(svc.Name); err != nil {
+			log.Printf("No parser for discovered service: %s", svc.Name)
+			continue
+		}
+
+		// Use discovered path, or magic path if enabled
+		path := svc.LogPath
+		if cfg.EnableMagicLogAccess && svc.MagicLogPath != "" {
+			path = svc.MagicLogPath
+		}
+		inputs = append(inputs, webInput(svc.Name, path, svc.Name, detect(svc.Name), wp, coll))
+	}
+
+	// Explicit Config Fallbacks
+	if cfg.NginxAccessLogPath != "" {
+		inputs = append(inputs, webInput("nginx_manual", cfg.NginxAccessLogPath, "nginx", detect("nginx"), wp, coll))
+	}
+	if cfg.NginxErrorLogPath != "" {
+		inputs = append(inputs, errorInput("nginx_manual", cfg.NginxErrorLogPath, coll))
+	}
+
+	// SSH Monitoring is distinct
+	if cfg.SSHAuthLogPath != "" {
+		inputs = append(inputs, sshInput("ssh", cfg.SSHAuthLogPath, wp.Analyzer, coll))
+	}
+
+	return inputs, nil
+}
+
+// envelope maps a config envelope name (validated by config) to ingest's
+func envelope(name string) ingest.Envelope {
+	switch name {
+	case config.EnvelopeCRI:
+		return ingest.EnvelopeCRI
+	case config.EnvelopeDocker:
+		return ingest.EnvelopeDocker
+	case config.EnvelopeAuto:
+		return ingest.EnvelopeAuto
+	}
+	return ingest.NoEnvelope
+}
+
+// customParsers compiles the parsers defined in the config file
+func customParsers(defs []config.ParserConfig) (map[string]customParser, error) {
+	builtin := make(map[string]bool)
+	for _, name := range parser.Names() {
+		builtin[name] = true
+	}
+
+	parsers := make(map[string]customParser, len(defs))
+	for i, def := range defs {
+		if builtin[def.Name] {
+			return nil, fmt.Errorf("parsers[%d].name: %q is a built-in parser", i, def.Name)
+		}
+		p, err := newCustomParser(def)
+		if err != nil {
+			return nil, fmt.Errorf("parsers[%d]: %v", i, err)
+		}
+		parsers[def.Name] = customParser{p, fmt.Sprintf("%+v", def)}
+	}
+	return parsers, nil
+}
+
+func newCustomParser(def config.ParserConfig) (parser.LogParser, error) {
+	switch def.Type {
+	case config.ParserJSON:
+		return parser.NewJSONParser(parser.JSONConfig{
+			Name:       def.Name,
+			Fields:     def.Fields,
+			TimeLayout: def.TimeLayout,
+		})
+	case config.ParserLogfmt:
+		return parser.NewLogfmtParser(parser.LogfmtConfig{
+			Name:       def.Name,
+			Fields:     def.Fields,
+			TimeLayout: def.TimeLayout,
+		})
+	}
+
+	return parser.NewGrokParser(parser.GrokConfig{
+		Name:               def.Name,
+		Patterns:           def.Patterns,
+		PatternDefinitions: def.PatternDefinitions,
+		Fields:             def.Fields,
+		TimeLayout:         def.TimeLayout,
+	})
+}
+
+// customParser is a compiled config-defined parser. The definition is kept
+// to restart the inputs using it when it changes on reload.
+type customParser struct {
+	parser.LogParser
+	definition string
+}
+
+// inputParser returns the parser constructor of a declared access input,
+// generated from its custom log format if one is set. The format (or custom
+// parser definition) is returned so changes to it (including in the server
+// config file) restart the input on reload.
+func inputParser(in config.InputConfig, custom map[string]customParser) (func() parser.LogParser, string, error) {
+	if c, ok := custom[in.Parser]; ok {
+		if in.LogFormat != "" || in.LogFormatName != "" {
+			return nil, "", fmt.Errorf("log_format: not supported with custom parser %q", in.Parser)
+		}
+		return shared(c.LogParser), c.definition, nil
+	}
+
+	format := in.LogFormat
+	if in.LogFormatName != "" {
+		var err error
+		format, err = parser.LoadFormat(in.Parser, in.ServerConfig, in.LogFormatName)
+		if err != nil {
+			return nil, "", fmt.Errorf("log_format_name: %v", err)
+		}
+	}
+	if format == "" {
+		if in.Parser == parser.Auto {
+			return detect(""), "", nil
+		}
+		p, err := parser.New(in.Parser)
+		if err != nil {
+			return nil, "", fmt.Errorf("parser: %v", err)
+		}
+		if _, ok := p.(parser.StatefulParser); ok {
+			// Each file has its own state (W3C #Fields)
+			return func() parser.LogParser { p, _ := parser.New(in.Parser); return p }, "", nil
+		}
+		return shared(p), "", nil
+	}
+	p, err := parser.NewFormat(in.Parser, format)
+	if err != nil {
+		return nil, "", fmt.Errorf("log_format: %v", err)
+	}
+	return shared(p), format, nil
+}
+
+// shared uses the same (stateless) parser for every file of an input
+func shared(p parser.LogParser) func() parser.LogParser {
+	return func() parser.LogParser { return p }
+}
+
+// detect gives each file its own format detector, preferring hint (a
+// parser name, may be empty) when several formats fit equally well
+func detect(hint string) func() parser.LogParser {
+	return func() parser.LogParser { return parser.NewAutoParser(hint) }
+}
+
+// webInput feeds an access log through the worker pool. newParser is called
+// for every file the input matches.
+func webInput(service, path, parserName string, newParser func() parser.LogParser, wp *worker.Pool, coll *collector.LogCollector) ingest.Input {
+	input := ingest.Input{
+		Key:     "access|" + service + "|" + path + "|" + parserName,
+		Service: service,
+		Path:    path,
+		NewAsyncHandler: func(f ingest.File) (ingest.Handler, func()) {
+			// Metric Initialization (Ensure they appear as 0 instead of missing)
+			// We initialize common vectors to ensure they show up in Prometheus output even if 0
+			// Done once the file is followed, so a reload that fails to
+			// build its inputs leaves no trace.
+			coll.WebRequests.WithLabelValues(f.Service, "GET", "200", "/", "unknown", "unknown").Add(0)
+			coll.WebRequestBytes.WithLabelValues(f.Service, "GET").Add(0)
+			coll.WebResponseBytes.WithLabelValues(f.Service, "GET", "unknown").Add(0)
+
+			p := newParser()
+			if grok, ok := p.(*parser.GrokParser); ok {
+				p = grok.WithObserve(func(pattern string, matched bool) {
+					result := "miss"
+					if matched {
+						result = "match"
+					}
+					coll.ParserPatterns.WithLabelValues(f.Service, grok.Name, pattern, result).Inc()
+				})
+			}
+			if auto, ok := p.(*parser.AutoParser); ok {
+				detected := ""
+				auto.OnDetect = func(format string) {
+					log.Printf("Detected %s log format for %s (%s)", format, f.Path, f.Service)
+					coll.SetLogFormat(f.Service, f.Path, detected, format)
+					detected = format
+				}
+			}
+			stateful, _ := p.(parser.StatefulParser)
+			return func(line string, done func()) {
+				lp := p
+				if stateful != nil {
+					// Lines are parsed concurrently; bind each to the state
+					// (e.g. W3C #Fields) in file order first
+					if lp = stateful.Next(line); lp == nil {
+						done()
+						return
+					}
+				}
+				wp.Submit(worker.Job{
+					ServiceName: f.Service,
+					LogPath:     f.Path,
+					Vhost:       f.Vhost,
+					Line:        line,
+					Parser:      lp,
+					Done:        done,
+				})
+			}, nil
+		},
+	}
+	// A file resumed from a checkpoint reads its directives again
+	if stateful, ok := newParser().(parser.StatefulParser); ok {
+		input.Header = stateful.Header
+	}
+	return input
+}
+
+// sshInput processes the sshd and sudo/su lines of an auth log directly
+// (no worker pool)
+func sshInput(service, path string, a *analyzer.Analyzer, coll *collector.LogCollector) ingest.Input {
+	return ingest.Input{
+		Key:     "ssh|" + service + "|" + path,
+		Service: service,
+		Path:    path,
+		NewHandler: func(f ingest.File) func(string) {
+			return func(line string) {
+				entry, err := parser.ParseSSHLine(line)
+				if err != nil {
+					return
+				}
+				if entry != nil {
+					coll.ProcessSSH(f.Service, entry)
+					return
+				}
+				if event := parser.ParsePrivilegeLine(line); event != nil {
+					coll.ProcessPrivilege(event, a.HighRiskCommand(event.Command))
+				}
+			}
+		},
+	}
+}
+
+// appInput counts the exceptions logged by an application. Stack traces
+// should be joined into single events with a multiline setting.
+func appInput(service, path string, coll *collector.LogCollector) ingest.Input {
+	return ingest.Input{
+		Key:     "app|" + service + "|" + path,
+		Service: service,
+		Path:    path,
+		NewHandler: func(f ingest.File) func(string) {
+			return func(event string) {
+				coll.ProcessApp(f.Service, event)
+			}
+		},
+	}
+}
+
+// auditInput reassembles the records of a Linux audit log into events.
+// Records of an event are written together, so each file gets its own
+// assembler.
+func auditInput(service, path string, coll *collector.LogCollector) ingest.Input {
+	return ingest.Input{
+		Key:     "audit|" + service + "|" + path,
+		Service: service,
+		Path:    path,
+		NewAsyncHandler: func(f ingest.File) (ingest.Handler, func()) {
+			h := newAuditHandler(coll, auditQuiet)
+			return h.Add, h.Stop
+		},
+	}
+}
+
+// auditQuiet is how long an audit log may be quiet before the events still
+// waiting for records are emitted
+const auditQuiet = 2 * time.Second
+
+// auditHandler assembles the events of one audit log. Lines are done (and
+// checkpointed) only once no event is pending, so a restart does not lose
+// the records of a half-assembled event.
+type auditHandler struct {
+	coll  *collector.LogCollector
+	quiet time.Duration
+
+	mu        sync.Mutex
+	assembler *parser.AuditAssembler
+	dones     []func() // Lines read since no event was pending
+	timer     *time.Timer
+}
+
+func newAuditHandler(coll *collector.LogCollector, quiet time.Duration) *auditHandler {
+	return &auditHandler{coll: coll, quiet: quiet, assembler: parser.NewAuditAssembler()}
+}
+
+// Add processes one line
+func (h *auditHandler) Add(line string, done func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.process(h.assembler.Add(line))
+	h.dones = append(h.dones, done)
+	if h.assembler.Pending() == 0 {
+		h.release()
+		return
+	}
+
+	if h.timer == nil {
+		h.timer = time.AfterFunc(h.quiet, h.Flush)
+	} else {
+		h.timer.Reset(h.quiet)
+	}
+}
+
+// Flush emits the pending events
+func (h *auditHandler) Flush() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.process(h.assembler.Flush())
+	h.release()
+}
+
+// Stop emits the pending events and cancels the flush timer
+func (h *auditHandler) Stop() {
+	h.mu.Lock()
+	if h.timer != nil {
+		h.timer.Stop()
+	}
+	h.mu.Unlock()
+	h.Flush()
+}
+
+func (h *auditHandler) process(events []*parser.AuditEvent) {
+	for _, event := range events {
+		h.coll.ProcessAudit(event)
+	}
+}
+
+func (h *auditHandler) release() {
+	for _, done := range h.dones {
+		done()
+	}
+	h.dones = nil
+}
+
+// firewallInput counts the packets logged by netfilter and looks for port
+// scans
+func firewallInput(service, path string, scans *anomaly.PortScanDetector, coll *collector.LogCollector) ingest.Input {
+	return ingest.Input{
+		Key:     "firewall|" + service + "|" + path,
+		Service: service,
+		Path:    path,
+		NewHandler: func(f ingest.File) func(string) {
+			return func(line string) {
+				if entry := parser.ParseFirewallLine(line); entry != nil {
+					coll.ProcessFirewall(entry, scans.CheckAt(entry.SrcIP, entry.DstPort, entry.Time))
+				}
+			}
+		},
+	}
+}
+
+// mailInput counts Postfix/Dovecot logins and rejects; failed logins go
+// through the same per-IP anomaly detector as web requests
+func mailInput(service, path string, ad *anomaly.AnomalyDetector, coll *collector.LogCollector) ingest.Input {
+	return ingest.Input{
+		Key:     "mail|" + service + "|" + path,
+		Service: service,
+		Path:    path,
+		NewHandler: func(f ingest.File) func(string) {
+			return func(line string) {
+				entry := parser.ParseMailLine(line)
+				if entry == nil {
+					return
+				}
+				var anomalyType anomaly.AnomalyType
+				if entry.Type == parser.MailAuthFailed && entry.IP != "" {
+					anomalyType = ad.CheckAuthFailureAt(entry.IP, entry.Attempts, entry.Time)
+				}
+				coll.ProcessMail(entry, anomalyType)
+			}
+		},
+	}
+}
+
+func compileOptional(pattern string) *regexp.Regexp {
+	if pattern == "" {
+		return nil
+	}
+	return regexp.MustCompile(pattern)
+}
+
+// errorInput processes an nginx/Apache error log directly (no worker pool)
+func errorInput(service, path string, coll *collector.LogCollector) ingest.Input {
+	return ingest.Input{
+		Key:     "error|" + service + "|" + path,
+		Service: service,
+		Path:    path,
+		NewHandler: func(f ingest.File) func(string) {
+			return func(line string) {
+				entry, err := parser.ParseErrorLine(line)
+				if err != nil {
+					return // Continuation lines, e.g. PHP stack traces
+				}
+				coll.ProcessError(f.Service, entry)
+			}
+		},
+	}
+}
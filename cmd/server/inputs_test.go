@@ -0,0 +1,150 @@
+package main
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"log-sentry/internal/collector"
+	"log-sentry/internal/config"
+	"log-sentry/internal/discovery"
+	"log-sentry/internal/enricher"
+	"log-sentry/internal/parser"
+)
+
+func TestBuildInputsDiscoveredServices(t *testing.T) {
+	cfg := config.Default()
+	cfg.NginxAccessLogPath, cfg.NginxErrorLogPath, cfg.SSHAuthLogPath = "", "", ""
+	services := []discovery.DetectedService{
+		{Name: "nginx", LogPath: "/var/log/nginx/access.log"},
+		{Name: "mysqld", LogPath: "/var/log/mysql/mysql.log"}, // No parser
+	}
+
+	coll := collector.NewLogCollector(enricher.NewEnricher())
+	inputs, err := buildInputs(cfg, services, nil, nil, coll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) != 1 || inputs[0].Service != "nginx" {
+		t.Errorf("got %d inputs (%+v); want nginx only", len(inputs), inputs)
+	}
+}
+
+func TestInputParserPerFile(t *testing.T) {
+	newParser, _, err := inputParser(config.InputConfig{Parser: "w3c"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Two files of a directory input, each with its own #Fields
+	files := []struct {
+		fields string
+		line   string
+		want   string // Path
+	}{
+		{"#Fields: date time cs-uri-stem sc-status", "2024-01-01 00:00:00 /a 200", "/a"},
+		{"#Fields: date time sc-status cs-uri-stem", "2024-01-01 00:00:00 404 /b", "/b"},
+	}
+	parsers := make([]parser.LogParser, len(files))
+	for i, f := range files {
+		parsers[i] = newParser()
+		if _, err := parsers[i].Parse(f.fields); err == nil {
+			t.Fatalf("directive %q parsed as a line", f.fields)
+		}
+	}
+	for i, f := range files {
+		got, err := parsers[i].Parse(f.line)
+		if err != nil {
+			t.Fatalf("file %d: %v", i, err)
+		}
+		if got.Path != f.want {
+			t.Errorf("file %d: path = %q; want %q", i, got.Path, f.want)
+		}
+	}
+}
+
+func TestWebInputHeader(t *testing.T) {
+	coll := collector.NewLogCollector(enricher.NewEnricher())
+	tests := []struct {
+		parser string
+		want   bool // Directives read again on resume
+	}{
+		{"w3c", true},
+		{"nginx", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.parser, func(t *testing.T) {
+			newParser, _, err := inputParser(config.InputConfig{Parser: tt.parser}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			in := webInput("web", "/var/log/web.log", tt.parser, newParser, nil, coll)
+			if got := in.Header != nil && in.Header("#Fields: date time"); got != tt.want {
+				t.Errorf("directive is header = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuditHandler(t *testing.T) {
+	// A failed open of a watched file whose EOE record never comes
+	lines := []string{
+		`type=SYSCALL msg=audit(1700000001.000:101): arch=c000003e syscall=257 success=no exit=-13 auid=4294967295 uid=33 comm="php-fpm" exe="/usr/sbin/php-fpm" key="secret"`,
+		`type=PATH msg=audit(1700000001.000:101): item=0 name="/etc/secret" nametype=NORMAL`,
+	}
+
+	tests := []struct {
+		name string
+		end  func(h *auditHandler)
+	}{
+		{"Stopped", func(h *auditHandler) { h.Stop() }},
+		{"Quiet", func(h *auditHandler) { time.Sleep(100 * time.Millisecond) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			coll := collector.NewLogCollector(enricher.NewEnricher())
+			h := newAuditHandler(coll, 20*time.Millisecond)
+			defer h.Stop()
+
+			var done atomic.Int32
+			for _, line := range lines {
+				h.Add(line, func() { done.Add(1) })
+			}
+			if n := done.Load(); n != 0 {
+				t.Errorf("%d lines done while the event is pending; want 0", n)
+			}
+
+			tt.end(h)
+			if n := done.Load(); n != int32(len(lines)) {
+				t.Errorf("%d lines done; want %d", n, len(lines))
+			}
+			if got := counterTotal(t, coll, "audit_watch_events_total"); got != 1 {
+				t.Errorf("audit watch events = %v; want 1", got)
+			}
+		})
+	}
+}
+
+// counterTotal returns the sum of a counter of coll over all label values
+func counterTotal(t *testing.T, coll *collector.LogCollector, name string) float64 {
+	t.Helper()
+	reg := prometheus.NewRegistry()
+	coll.Register(reg)
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := 0.0
+	for _, mf := range families {
+		if mf.GetName() == name {
+			for _, m := range mf.GetMetric() {
+				total += m.GetCounter().GetValue()
+			}
+		}
+	}
+	return total
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
@@ -8,32 +9,41 @@ import (
 
 	"log-sentry/internal/analyzer"
 	"log-sentry/internal/anomaly"
+	"log-sentry/internal/checkpoint"
 	"log-sentry/internal/collector"
 	"log-sentry/internal/config"
 	"log-sentry/internal/discovery"
 	"log-sentry/internal/enricher"
+	"log-sentry/internal/ingest"
 	"log-sentry/internal/intelligence"
 	"log-sentry/internal/journald"
 	"log-sentry/internal/monitor"
-	"log-sentry/internal/parser"
 	"log-sentry/internal/syslog"
-	"log-sentry/internal/tailer"
 	"log-sentry/internal/worker"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
+// inputRescanInterval is how often directory and glob inputs are re-expanded
+const inputRescanInterval = 10 * time.Second
+
 func main() {
+	configPath := flag.String("config", "", "Path to YAML config file (default $LOG_SENTRY_CONFIG or "+config.DefaultConfigPath+")")
+	flag.Parse()
+
 	// 1. Load Configuration
-	cfg := config.Load()
-	log.Printf("Starting Log Sentry V2 on port %d...", cfg.Port)
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	log.Printf("Starting Log Sentry V2 on port %d...", cfg.Outputs.Prometheus.Port)
 
 	// 0. Initialize CrowdSec Bouncer (Optional)
 	var bouncer *intelligence.CrowdSecBouncer
-	if cfg.EnableCrowdSec {
+	if cfg.CrowdSec.Enabled {
 		var err error
-		bouncer, err = intelligence.NewCrowdSecBouncer(cfg.CrowdSecAPIKey, cfg.CrowdSecLAPIURL)
+		bouncer, err = intelligence.NewCrowdSecBouncer(cfg.CrowdSec.APIKey, cfg.CrowdSec.LAPIURL)
 		if err != nil {
 			log.Fatalf("Failed to initialize CrowdSec Bouncer: %v", err)
 		}
@@ -54,154 +64,99 @@ func main() {
 	coll.Register(prometheus.DefaultRegisterer)
 
 	secAnalyzer := analyzer.NewAnalyzer()
-	anomalyDetector := anomaly.NewAnomalyDetector()
+	anomalyDetector := anomaly.NewAnomalyDetector(cfg.Anomaly.Threshold404, cfg.Anomaly.Threshold500, cfg.Anomaly.ThresholdAuthFailures, cfg.Anomaly.Window)
 	// enricher initialized earlier as 'enr'
-	autoDisco := discovery.NewAutoDiscover()
 
 	// 2a. Initialize Worker Pool
-	wp := worker.NewPool(5, coll, secAnalyzer, anomalyDetector, enr)
+	wp := worker.NewPool(cfg.Workers, coll, secAnalyzer, anomalyDetector, enr)
 	wp.Start()
 
 	// 3. Auto-Discovery
-	log.Println("Running Auto-Discovery...")
-	services, err := autoDisco.Scan()
-	if err != nil {
-		log.Printf("Auto-discovery warning: %v", err)
+	var services []discovery.DetectedService
+	if cfg.Discovery.Enabled {
+		log.Println("Running Auto-Discovery...")
+		services, err = discovery.NewAutoDiscover().Scan()
+		if err != nil {
+			log.Printf("Auto-discovery warning: %v", err)
+		}
+		for _, svc := range services {
+			log.Printf("Discovered service: %s (PID: %d)", svc.Name, svc.PID)
+		}
 	}
 
 	// 4a. Start Syslog Server (Network Ingestion)
-	syslogServer := syslog.NewSyslogServer(5140, coll, secAnalyzer, enr)
-	syslogServer.Start()
-
-	// 4b. Start Monitoring for Discovered/Configured Services
-	monitoredCount := 0
+	if cfg.Syslog.Enabled {
+		syslogServer := syslog.NewSyslogServer(cfg.Syslog.Port, coll, secAnalyzer, enr)
+		syslogServer.Start()
+	}
 
-	// Helper to start monitoring a web service
-	monitorService := func(name, path string, p parser.LogParser) {
-		if path == "" {
-			return
+	// 4b. Read offsets (resume where the previous run stopped)
+	var store *checkpoint.Store
+	if cfg.Checkpoint.Enabled {
+		store, err = checkpoint.Open(cfg.Checkpoint.Path)
+		if err != nil {
+			log.Fatalf("Failed to open checkpoint store: %v", err)
 		}
-		log.Printf("Monitoring %s logs at: %s", name, path)
-
-		// Metric Initialization (Ensure they appear as 0 instead of missing)
-		// We initialize common vectors to ensure they show up in Prometheus output even if 0
-		coll.WebRequests.WithLabelValues(name, "GET", "200", "/", "unknown", "unknown").Add(0)
-		coll.WebRequestBytes.WithLabelValues(name, "GET").Add(0)
-		coll.WebResponseBytes.WithLabelValues(name, "GET", "unknown").Add(0)
-		
-		startWebMonitoring(name, path, p, wp)
-		monitoredCount++
+		store.Start(cfg.Checkpoint.FlushInterval)
 	}
 
-	// 4c. Auto-Discovered Services
-	for _, svc := range services {
-		log.Printf("Discovered service: %s (PID: %d)", svc.Name, svc.PID)
-		var p parser.LogParser
-		switch svc.Name {
-		case "nginx":
-			p = &parser.NginxParser{}
-		case "apache", "apache2", "httpd":
-			p = &parser.ApacheParser{}
-		case "caddy":
-			p = &parser.CaddyParser{}
-		case "tomcat":
-			p = &parser.TomcatParser{}
-		case "traefik":
-			p = &parser.TraefikParser{}
-		case "haproxy":
-			p = &parser.HAProxyParser{}
-		case "envoy":
-			p = &parser.EnvoyParser{}
-		case "lighttpd":
-			p = &parser.LighttpdParser{}
-		default:
-			log.Printf("No parser for discovered service: %s", svc.Name)
-			continue
-		}
-		
-		// Use discovered path, or magic path if enabled
-		path := svc.LogPath
-		if cfg.EnableMagicLogAccess && svc.MagicLogPath != "" {
-			path = svc.MagicLogPath
-			log.Printf("Auto-ingesting %s via magic path: %s", svc.Name, path)
-		}
-		
-		monitorService(svc.Name, path, p)
+	a := &agent{
+		configPath: *configPath,
+		services:   services,
+		store:      store,
+		inputs:     ingest.NewManager(store, cfg.Backfill.Enabled),
+		wp:         wp,
+		coll:       coll,
+		analyzer:   secAnalyzer,
+		anomaly:    anomalyDetector,
+		portScan:   anomaly.NewPortScanDetector(cfg.Anomaly.PortScanPorts, cfg.Anomaly.PortScanWindow),
 	}
 
-	// 4d. Explicit Config Fallbacks (if not discovered or forced)
-	if monitoredCount == 0 || cfg.NginxAccessLogPath != "" {
-		log.Println("Loading configured log paths...")
-		monitorService("nginx_manual", cfg.NginxAccessLogPath, &parser.NginxParser{})
+	// 4c. V2.2 Security Monitors
+	// SSL Monitor
+	if cfg.Monitors.SSL.Enabled {
+		a.ssl = monitor.NewSSLMonitor()
+		a.ssl.Register(prometheus.DefaultRegisterer)
+		a.ssl.Start(cfg.Monitors.SSL.Interval)
 	}
-	
-	// 4e. V2.2 Security Monitors
-	// SSL Monitor (Default check localhost:443)
-	sslMon := monitor.NewSSLMonitor()
-	sslMon.Register(prometheus.DefaultRegisterer)
-	sslMon.AddTarget("localhost:443")
-	sslMon.Start(1 * time.Hour) // Check hourly
 
 	// File Integrity Monitor (FIM)
-	fim := monitor.NewFIM()
-	fim.Register(prometheus.DefaultRegisterer)
-	fim.AddPath("/etc/passwd") 
-	fim.AddPath(cfg.NginxAccessLogPath)
-	fim.Start(30 * time.Second)
+	if cfg.Monitors.FIM.Enabled {
+		a.fim = monitor.NewFIM()
+		a.fim.Register(prometheus.DefaultRegisterer)
+		a.fim.Start(cfg.Monitors.FIM.Interval)
+	}
 
 	// Process Sentinel
-	procSent := monitor.NewProcessSentinel()
-	procSent.Register(prometheus.DefaultRegisterer)
-	procSent.Start(30 * time.Second)
+	if cfg.Monitors.Process.Enabled {
+		a.proc = monitor.NewProcessSentinel()
+		a.proc.Register(prometheus.DefaultRegisterer)
+		a.proc.Start(cfg.Monitors.Process.Interval)
+	}
 
-	// SSH Monitoring is distinct
-	startSSHMonitoring(cfg.SSHAuthLogPath, coll)
+	// 4d. Inputs (configured, discovered, SSH) and reloadable settings
+	if err := a.apply(cfg); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	a.inputs.Watch(inputRescanInterval)
+	go a.handleSignals()
+
+	// 4e. System Integration
+	if cfg.Monitors.Journald.Enabled {
+		go journald.StartReader(wp)
+	}
 
-    // 4f. System Integration
-    go journald.StartReader(wp) // Uncomment to enable if journalctl is present
-    
 	// 5. Start HTTP Server
-	http.Handle("/metrics", promhttp.Handler())
+	http.Handle(cfg.Outputs.Prometheus.Path, promhttp.Handler())
 	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	})
-	
-	addr := fmt.Sprintf(":%d", cfg.Port)
+	if cfg.Admin.EnableReload {
+		http.HandleFunc("/-/reload", a.handleReload)
+	}
+
+	addr := fmt.Sprintf(":%d", cfg.Outputs.Prometheus.Port)
 	log.Printf("Listening on %s", addr)
 	log.Fatal(http.ListenAndServe(addr, nil))
 }
-
-func startWebMonitoring(service, path string, p parser.LogParser, wp *worker.Pool) {
-	lines := make(chan string)
-	tailer.TailFile(path, lines)
-
-	go func() {
-		for line := range lines {
-			wp.Submit(worker.Job{
-				ServiceName: service,
-				LogPath:     path,
-				Line:        line,
-				Parser:      p,
-			})
-		}
-	}()
-}
-
-func startSSHMonitoring(path string, coll *collector.LogCollector) {
-	log.Printf("Monitoring SSH logs at: %s", path)
-	lines := make(chan string)
-	tailer.TailFile(path, lines)
-
-	go func() {
-		for line := range lines {
-			entry, err := parser.ParseSSHLine(line)
-			if err != nil {
-				continue
-			}
-			if entry != nil {
-				coll.ProcessSSH(entry)
-			}
-		}
-	}()
-}
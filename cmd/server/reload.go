@@ -0,0 +1,181 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+
+	"log-sentry/internal/analyzer"
+	"log-sentry/internal/anomaly"
+	"log-sentry/internal/checkpoint"
+	"log-sentry/internal/collector"
+	"log-sentry/internal/config"
+	"log-sentry/internal/discovery"
+	"log-sentry/internal/ingest"
+	"log-sentry/internal/monitor"
+	"log-sentry/internal/worker"
+)
+
+// agent holds the long-lived components. They are created once at startup
+// and reconfigured in place on reload, so Prometheus counters and detector
+// state survive a config change.
+type agent struct {
+	mu         sync.Mutex
+	configPath string
+	cfg        *config.Config
+	services   []discovery.DetectedService // Discovered once at startup
+	store      *checkpoint.Store           // nil when checkpointing is disabled
+
+	inputs   *ingest.Manager
+	wp       *worker.Pool
+	coll     *collector.LogCollector
+	analyzer *analyzer.Analyzer
+	anomaly  *anomaly.AnomalyDetector
+	portScan *anomaly.PortScanDetector
+
+	// Monitors are nil when disabled
+	ssl  *monitor.SSLMonitor
+	fim  *monitor.FIM
+	proc *monitor.ProcessSentinel
+}
+
+// apply pushes the reloadable parts of cfg into the running components.
+// Nothing is changed if cfg cannot be applied: every step that can fail
+// runs before the first change.
+func (a *agent) apply(cfg *config.Config) error {
+	rules, err := analyzer.Rules{
+		SQLi:              cfg.Analyzer.SQLi,
+		XSS:               cfg.Analyzer.XSS,
+		PathTraversal:     cfg.Analyzer.PathTraversal,
+		Scanner:           cfg.Analyzer.Scanner,
+		ExfiltrationBytes: cfg.Analyzer.ExfiltrationBytes,
+		HighRiskCommands:  cfg.Analyzer.HighRiskCommands,
+	}.Compile()
+	if err != nil {
+		return err
+	}
+	inputs, err := buildInputs(cfg, a.services, a.wp, a.portScan, a.coll)
+	if err != nil {
+		return err
+	}
+
+	a.analyzer.SetRuleSet(rules)
+	a.anomaly.SetThresholds(cfg.Anomaly.Threshold404, cfg.Anomaly.Threshold500, cfg.Anomaly.ThresholdAuthFailures, cfg.Anomaly.Window)
+	a.portScan.SetThresholds(cfg.Anomaly.PortScanPorts, cfg.Anomaly.PortScanWindow)
+	a.coll.SetSSHSessionMaxAge(cfg.SSH.SessionMaxAge)
+
+	if a.ssl != nil {
+		a.ssl.SetTargets(cfg.Monitors.SSL.Targets)
+	}
+	if a.fim != nil {
+		paths := append([]string(nil), cfg.Monitors.FIM.Paths...)
+		if cfg.NginxAccessLogPath != "" {
+			paths = append(paths, cfg.NginxAccessLogPath)
+		}
+		a.fim.SetPaths(paths)
+	}
+	if a.proc != nil {
+		a.proc.SetBlacklist(cfg.Monitors.Process.Blacklist)
+	}
+
+	a.inputs.Apply(inputs)
+	a.cfg = cfg
+	return nil
+}
+
+// reload re-reads the config file and applies it. On error the previous
+// configuration stays in effect.
+func (a *agent) reload() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	cfg, err := config.Load(a.configPath)
+	if err != nil {
+		return err
+	}
+	warnRestartRequired(a.cfg, cfg)
+	if err := a.apply(cfg); err != nil {
+		return err
+	}
+	log.Printf("Configuration reloaded (%d inputs running)", a.inputs.Count())
+	return nil
+}
+
+func (a *agent) handleSignals() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
+	for sig := range ch {
+		if sig != syscall.SIGHUP {
+			a.shutdown()
+			os.Exit(0)
+		}
+		log.Println("SIGHUP received, reloading configuration...")
+		if err := a.reload(); err != nil {
+			log.Printf("Reload failed, keeping previous configuration: %v", err)
+		}
+	}
+}
+
+// shutdown stops tailing, lets queued lines finish and persists the read
+// offsets so the next start resumes exactly where this one stopped.
+func (a *agent) shutdown() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	log.Println("Shutting down...")
+	a.inputs.Stop()
+	if !a.wp.Drain(5 * time.Second) {
+		log.Println("Timed out waiting for queued lines to be processed")
+	}
+	if a.store != nil {
+		if err := a.store.Flush(); err != nil {
+			log.Printf("Checkpoint flush failed: %v", err)
+		}
+	}
+}
+
+// handleReload serves POST /-/reload
+func (a *agent) handleReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := a.reload(); err != nil {
+		log.Printf("Reload failed, keeping previous configuration: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+}
+
+// warnRestartRequired logs settings that changed but are only read at startup
+func warnRestartRequired(old, cur *config.Config) {
+	changed := func(name string, a, b interface{}) {
+		if !reflect.DeepEqual(a, b) {
+			log.Printf("Config reload: %s changed, restart required for it to take effect", name)
+		}
+	}
+	changed("workers", old.Workers, cur.Workers)
+	changed("checkpoint", old.Checkpoint, cur.Checkpoint)
+	changed("backfill", old.Backfill, cur.Backfill)
+	changed("discovery", old.Discovery, cur.Discovery)
+	changed("enable_magic_log_access", old.EnableMagicLogAccess, cur.EnableMagicLogAccess)
+	changed("syslog", old.Syslog, cur.Syslog)
+	changed("crowdsec", old.CrowdSec, cur.CrowdSec)
+	changed("outputs", old.Outputs, cur.Outputs)
+	changed("admin", old.Admin, cur.Admin)
+	changed("monitors.ssl.enabled", old.Monitors.SSL.Enabled, cur.Monitors.SSL.Enabled)
+	changed("monitors.ssl.interval", old.Monitors.SSL.Interval, cur.Monitors.SSL.Interval)
+	changed("monitors.fim.enabled", old.Monitors.FIM.Enabled, cur.Monitors.FIM.Enabled)
+	changed("monitors.fim.interval", old.Monitors.FIM.Interval, cur.Monitors.FIM.Interval)
+	changed("monitors.process.enabled", old.Monitors.Process.Enabled, cur.Monitors.Process.Enabled)
+	changed("monitors.process.interval", old.Monitors.Process.Interval, cur.Monitors.Process.Interval)
+	changed("monitors.journald", old.Monitors.Journald, cur.Monitors.Journald)
+}
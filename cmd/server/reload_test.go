@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"log-sentry/internal/analyzer"
+	"log-sentry/internal/anomaly"
+	"log-sentry/internal/collector"
+	"log-sentry/internal/config"
+	"log-sentry/internal/enricher"
+	"log-sentry/internal/ingest"
+	"log-sentry/internal/worker"
+)
+
+// testConfig is an agent config without the legacy log paths, followed by
+// the declared inputs
+const testConfig = `
+nginx_access_log_path: ""
+nginx_error_log_path: ""
+ssh_auth_log_path: ""
+checkpoint:
+  enabled: false
+anomaly:
+  threshold_404: %d
+inputs:
+`
+
+func writeAgentConfig(t *testing.T, path string, threshold404 int, inputs string) {
+	t.Helper()
+	body := fmt.Sprintf(testConfig, threshold404) + inputs
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestAgent(t *testing.T, configPath string) *agent {
+	t.Helper()
+	enr := enricher.NewEnricher()
+	coll := collector.NewLogCollector(enr)
+	ad := anomaly.NewAnomalyDetector(10, 20, 10, time.Minute)
+	a := &agent{
+		configPath: configPath,
+		inputs:     ingest.NewManager(nil, false),
+		wp:         worker.NewPool(1, coll, analyzer.NewAnalyzer(), ad, enr),
+		coll:       coll,
+		analyzer:   analyzer.NewAnalyzer(),
+		anomaly:    ad,
+		portScan:   anomaly.NewPortScanDetector(20, time.Minute),
+	}
+	t.Cleanup(a.inputs.Stop)
+	return a
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(logPath, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	appInput := "  - service: app\n    type: app\n    path: " + logPath + "\n"
+
+	configPath := filepath.Join(dir, "config.yaml")
+	writeAgentConfig(t, configPath, 5, appInput)
+	a := newTestAgent(t, configPath)
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.apply(cfg); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if got := a.inputs.Count(); got != 1 {
+		t.Fatalf("got %d inputs running; want 1", got)
+	}
+
+	// A failed reload leaves the previous config and inputs in place
+	bad := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{"Invalid YAML", "inputs: [", "yaml"},
+		{"Invalid config", "anomaly:\n  threshold_404: 0\n", "anomaly.threshold_404"},
+		{"Input fails to build", "inputs:\n  - service: fresh\n    path: " + logPath + "\n    parser: nginx\n  - service: web\n    path: " + logPath + "\n    parser: nginx\n    log_format_name: main\n    server_config: " + filepath.Join(dir, "missing.conf") + "\n", "log_format_name"},
+	}
+	for _, tt := range bad {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(configPath, []byte(tt.body), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			err := a.reload()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("reload: got error %v; want %q", err, tt.wantErr)
+			}
+			if a.cfg != cfg {
+				t.Error("config replaced by failed reload")
+			}
+			if got := a.inputs.Count(); got != 1 {
+				t.Errorf("got %d inputs running; want 1", got)
+			}
+			if got := a.anomaly.Threshold404; got != 5 {
+				t.Errorf("got threshold_404 %d; want 5", got)
+			}
+			if hasSeries(t, a.coll, "http_requests_total", "service", "fresh") {
+				t.Error("metrics of a rejected input initialized")
+			}
+		})
+	}
+
+	// A valid reload replaces both
+	writeAgentConfig(t, configPath, 7, "")
+	if err := a.reload(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if a.cfg == cfg || a.cfg.Anomaly.Threshold404 != 7 {
+		t.Error("config not replaced by reload")
+	}
+	if got := a.inputs.Count(); got != 0 {
+		t.Errorf("got %d inputs running; want 0", got)
+	}
+	if got := a.anomaly.Threshold404; got != 7 {
+		t.Errorf("got threshold_404 %d; want 7", got)
+	}
+}
+
+// hasSeries reports whether the metric name of coll has a series with the
+// label set to value
+func hasSeries(t *testing.T, coll *collector.LogCollector, name, label, value string) bool {
+	t.Helper()
+	reg := prometheus.NewRegistry()
+	coll.Register(reg)
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mf := range families {
+		if mf.GetName() != name {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == label && l.GetValue() == value {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -1,7 +1,10 @@
 package analyzer
 
 import (
+	"fmt"
 	"regexp"
+	"sort"
+	"sync"
 )
 
 type AttackType string
@@ -15,28 +18,147 @@ const (
 )
 
 type Analyzer struct {
-	sqliRegex    *regexp.Regexp
-	xssRegex     *regexp.Regexp
+	mu            sync.RWMutex
+	sqliRegex     *regexp.Regexp
+	xssRegex      *regexp.Regexp
 	pathTravRegex *regexp.Regexp
-	scannerRegex *regexp.Regexp
+	scannerRegex  *regexp.Regexp
+	exfilBytes    int
+	highRisk      []commandRule // Sorted by name
 }
 
-func NewAnalyzer() *Analyzer {
-	return &Analyzer{
+type commandRule struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// Rules holds the detection patterns. Empty patterns (or a zero
+// ExfiltrationBytes) fall back to the built-in defaults.
+type Rules struct {
+	SQLi              string
+	XSS               string
+	PathTraversal     string
+	Scanner           string
+	ExfiltrationBytes int
+
+	// HighRiskCommands maps rule names to patterns of commands run through
+	// sudo that are worth flagging. An empty map keeps the built-in rules.
+	HighRiskCommands map[string]string
+}
+
+// DefaultRules returns the built-in detection patterns
+func DefaultRules() Rules {
+	return Rules{
 		// Basic SQLi patterns: UNION SELECT, OR 1=1, --, etc.
-		sqliRegex: regexp.MustCompile(`(?i)(union\s+select|or\s+1=1|\s+or\s+true|--|;\s*drop\s+table)`),
-		
+		SQLi: `(?i)(union\s+select|or\s+1=1|\s+or\s+true|--|;\s*drop\s+table)`,
+
 		// Basic XSS patterns: <script>, javascript:, on(event)=
-		xssRegex: regexp.MustCompile(`(?i)(<script|javascript:|on\w+=|alert\()`),
+		XSS: `(?i)(<script|javascript:|on\w+=|alert\()`,
 
 		// Path Traversal: ../..
-		pathTravRegex: regexp.MustCompile(`\.\./\.\.`),
+		PathTraversal: `\.\./\.\.`,
 
 		// Common Scanners User-Agents (simplified)
-		scannerRegex: regexp.MustCompile(`(?i)(nessus|nmap|nikto|sqlmap|burp)`),
+		Scanner: `(?i)(nessus|nmap|nikto|sqlmap|burp)`,
+
+		ExfiltrationBytes: 100 * 1024 * 1024, // 100MB
+
+		HighRiskCommands: map[string]string{
+			// Interactive shells: sudo -i, sudo bash, sudo su
+			"shell": `^(\S*/)?((ba|da|z|k|c|tc|fi)?sh|su)(\s+-\S*)*\s*$`,
+			// chmod +s / u+s / 4755
+			"setuid": `\bchmod\s+(.*\s)?([ugoa]*\+[rwxXt]*s|[0-7]?[2467][0-7]{3}\b)`,
+			// curl ... | sh
+			"pipe_to_shell": `\b(curl|wget)\b.*\|\s*(sudo\s+)?(\S*/)?(ba|da|z)?sh\b`,
+		},
 	}
 }
 
+func NewAnalyzer() *Analyzer {
+	a := &Analyzer{}
+	if err := a.SetRules(DefaultRules()); err != nil {
+		panic(err) // Built-in patterns always compile
+	}
+	return a
+}
+
+// RuleSet is a compiled Rules, see Compile
+type RuleSet struct {
+	sqli, xss, pathTrav, scanner *regexp.Regexp
+	exfilBytes                   int
+	highRisk                     []commandRule
+}
+
+// Compile compiles r, filling in the defaults
+func (r Rules) Compile() (*RuleSet, error) {
+	def := DefaultRules()
+	compile := func(name, pattern, fallback string) (*regexp.Regexp, error) {
+		if pattern == "" {
+			pattern = fallback
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("analyzer rule %s: %v", name, err)
+		}
+		return re, nil
+	}
+
+	var rs RuleSet
+	var err error
+	if rs.sqli, err = compile("sqli", r.SQLi, def.SQLi); err != nil {
+		return nil, err
+	}
+	if rs.xss, err = compile("xss", r.XSS, def.XSS); err != nil {
+		return nil, err
+	}
+	if rs.pathTrav, err = compile("path_traversal", r.PathTraversal, def.PathTraversal); err != nil {
+		return nil, err
+	}
+	if rs.scanner, err = compile("scanner", r.Scanner, def.Scanner); err != nil {
+		return nil, err
+	}
+	rs.exfilBytes = r.ExfiltrationBytes
+	if rs.exfilBytes <= 0 {
+		rs.exfilBytes = def.ExfiltrationBytes
+	}
+	commands := r.HighRiskCommands
+	if len(commands) == 0 {
+		commands = def.HighRiskCommands
+	}
+	for name, pattern := range commands {
+		re, err := compile("high_risk_commands."+name, pattern, "")
+		if err != nil {
+			return nil, err
+		}
+		rs.highRisk = append(rs.highRisk, commandRule{name, re})
+	}
+	sort.Slice(rs.highRisk, func(i, j int) bool { return rs.highRisk[i].name < rs.highRisk[j].name })
+	return &rs, nil
+}
+
+// SetRules compiles and swaps in a new rule set. On error the current
+// rules are kept.
+func (a *Analyzer) SetRules(r Rules) error {
+	rs, err := r.Compile()
+	if err != nil {
+		return err
+	}
+	a.SetRuleSet(rs)
+	return nil
+}
+
+// SetRuleSet swaps in a rule set compiled beforehand
+func (a *Analyzer) SetRuleSet(rs *RuleSet) {
+	a.mu.Lock()
+	a.sqliRegex = rs.sqli
+	a.xssRegex = rs.xss
+	a.pathTravRegex = rs.pathTrav
+	a.scannerRegex = rs.scanner
+	a.exfilBytes = rs.exfilBytes
+	a.highRisk = rs.highRisk
+	a.mu.Unlock()
+}
+
 type AttackResult struct {
 	Detected bool
 	Type     string
@@ -45,6 +167,9 @@ type AttackResult struct {
 
 // DetectAttack analyzes the request path, query params (if in path), and user agent
 func (a *Analyzer) DetectAttack(path, userAgent string) AttackResult {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
 	if a.sqliRegex.MatchString(path) {
 		return AttackResult{Detected: true, Type: "SQL Injection", Severity: "critical"}
 	}
@@ -63,9 +188,26 @@ func (a *Analyzer) DetectAttack(path, userAgent string) AttackResult {
 
 // CheckDataExfiltration checks for unusually large response bodies
 func (a *Analyzer) CheckDataExfiltration(bytesSent int) AttackResult {
-	const OneHundredMB = 100 * 1024 * 1024
-	if bytesSent > OneHundredMB {
+	a.mu.RLock()
+	limit := a.exfilBytes
+	a.mu.RUnlock()
+
+	if bytesSent > limit {
 		return AttackResult{Detected: true, Type: "Data Exfiltration (Large Download)", Severity: "high"}
 	}
 	return AttackResult{Detected: false}
 }
+
+// HighRiskCommand returns the name of the first high-risk command rule
+// matching command, or "" if none does
+func (a *Analyzer) HighRiskCommand(command string) string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	for _, rule := range a.highRisk {
+		if rule.re.MatchString(command) {
+			return rule.name
+		}
+	}
+	return ""
+}
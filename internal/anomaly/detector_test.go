@@ -0,0 +1,21 @@
+package anomaly
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckAuthFailureAt(t *testing.T) {
+	ad := NewAnomalyDetector(10, 20, 5, time.Minute)
+	start := time.Date(2023, 12, 12, 14, 0, 0, 0, time.UTC)
+
+	if got := ad.CheckAuthFailureAt("203.0.113.9", 3, start); got != "" {
+		t.Errorf("3 failures: got %q", got)
+	}
+	if got := ad.CheckAuthFailureAt("203.0.113.9", 3, start.Add(time.Second)); got != BruteForce {
+		t.Errorf("6 failures: got %q; want %q", got, BruteForce)
+	}
+	if got := ad.CheckAuthFailureAt("203.0.113.9", 1, start.Add(time.Minute)); got != "" {
+		t.Errorf("next window: got %q", got)
+	}
+}
@@ -0,0 +1,96 @@
+package anomaly
+
+import (
+	"sync"
+	"time"
+)
+
+type portStats struct {
+	Ports       map[int]bool // Distinct destination ports in the window
+	Reported    bool         // The scan was already reported this window
+	WindowStart time.Time    // Event time the current window opened at
+	LastSeen    time.Time    // Wall-clock time of the last packet, for cleanup
+}
+
+// PortScanDetector flags a source IP once it reaches Threshold distinct
+// destination ports within Window
+type PortScanDetector struct {
+	mu        sync.Mutex
+	Stats     map[string]*portStats
+	Threshold int
+	Window    time.Duration
+}
+
+// NewPortScanDetector creates a detector flagging an IP once it hits
+// threshold distinct ports within window. Defaults are 20 ports per minute.
+func NewPortScanDetector(threshold int, window time.Duration) *PortScanDetector {
+	d := &PortScanDetector{
+		Stats:     make(map[string]*portStats),
+		Threshold: threshold,
+		Window:    window,
+	}
+	go d.cleanupLoop()
+	return d
+}
+
+// SetThresholds updates the detection threshold in place, keeping the
+// per-IP state
+func (d *PortScanDetector) SetThresholds(threshold int, window time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.Threshold = threshold
+	d.Window = window
+}
+
+func (d *PortScanDetector) cleanupLoop() {
+	for {
+		d.mu.Lock()
+		window := d.Window
+		d.mu.Unlock()
+		time.Sleep(window)
+
+		d.mu.Lock()
+		now := time.Now()
+		for ip, stat := range d.Stats {
+			if now.Sub(stat.LastSeen) > d.Window {
+				delete(d.Stats, ip)
+			}
+		}
+		d.mu.Unlock()
+	}
+}
+
+// CheckAt records a packet from ip to port at ts (zero means now) and
+// reports whether it completes a scan. A scan is reported once per window,
+// when the threshold is reached. Windows follow the log's timestamps, as
+// in AnomalyDetector.CheckAt.
+func (d *PortScanDetector) CheckAt(ip string, port int, ts time.Time) bool {
+	if ip == "" || port == 0 {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	if ts.IsZero() {
+		ts = now
+	}
+
+	stat, exists := d.Stats[ip]
+	if !exists || ts.Sub(stat.WindowStart) >= d.Window {
+		stat = &portStats{Ports: make(map[int]bool), WindowStart: ts}
+		d.Stats[ip] = stat
+	}
+	stat.LastSeen = now
+
+	if stat.Reported {
+		return false
+	}
+	stat.Ports[port] = true
+	if len(stat.Ports) >= d.Threshold {
+		stat.Reported = true
+		stat.Ports = nil // Not needed for the rest of the window
+		return true
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package anomaly
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPortScanDetector(t *testing.T) {
+	d := NewPortScanDetector(3, time.Minute)
+	start := time.Date(2023, 12, 12, 14, 0, 0, 0, time.UTC)
+
+	// Repeated ports do not count, the scan is reported once per window
+	checks := []struct {
+		port   int
+		offset time.Duration
+		want   bool
+	}{
+		{22, 0, false},
+		{22, time.Second, false},
+		{23, 2 * time.Second, false},
+		{25, 3 * time.Second, true},
+		{80, 4 * time.Second, false},
+		// A new window starts over
+		{81, time.Minute, false},
+		{82, time.Minute, false},
+		{83, time.Minute, true},
+	}
+	for i, c := range checks {
+		if got := d.CheckAt("203.0.113.9", c.port, start.Add(c.offset)); got != c.want {
+			t.Errorf("check %d (port %d): got %v; want %v", i, c.port, got, c.want)
+		}
+	}
+	if d.CheckAt("198.51.100.7", 22, start) {
+		t.Error("other source flagged")
+	}
+}
@@ -0,0 +1,57 @@
+package checkpoint
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileID identifies a file independently of its name
+type FileID struct {
+	Dev   uint64
+	Inode uint64
+	Size  int64
+}
+
+// Identify returns the device/inode identity of path
+func Identify(path string) (FileID, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return FileID{}, err
+	}
+	return identifyInfo(info)
+}
+
+// FindRotated looks for the file previously known as path among its rotated
+// siblings (path.1, path-20240101, ...) by inode. Compressed files are skipped
+// since they cannot be resumed at a byte offset. Returns "" if none matches.
+func FindRotated(path string, pos Position) string {
+	if pos.Inode == 0 {
+		return ""
+	}
+	dir, base := filepath.Split(path)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if name == base || !strings.HasPrefix(name, base) || isCompressed(name) {
+			continue
+		}
+		candidate := filepath.Join(dir, name)
+		id, err := Identify(candidate)
+		if err == nil && pos.Matches(id) {
+			return candidate
+		}
+	}
+	return ""
+}
+
+func isCompressed(name string) bool {
+	switch filepath.Ext(name) {
+	case ".gz", ".zst", ".bz2", ".xz", ".zip":
+		return true
+	}
+	return false
+}
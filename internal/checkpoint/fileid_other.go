@@ -0,0 +1,13 @@
+//go:build !unix
+
+package checkpoint
+
+import (
+	"os"
+)
+
+// Without inode support only the size is known, so rotation is detected by
+// truncation alone.
+func identifyInfo(info os.FileInfo) (FileID, error) {
+	return FileID{Size: info.Size()}, nil
+}
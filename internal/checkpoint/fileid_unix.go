@@ -0,0 +1,17 @@
+//go:build unix
+
+package checkpoint
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+func identifyInfo(info os.FileInfo) (FileID, error) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return FileID{}, fmt.Errorf("no inode information for %s", info.Name())
+	}
+	return FileID{Dev: uint64(st.Dev), Inode: uint64(st.Ino), Size: info.Size()}, nil
+}
@@ -0,0 +1,167 @@
+package checkpoint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Position records how far a specific file has been processed.
+// Dev/Inode identify the file the offset belongs to, so a rotated file is
+// not mistaken for the one now living at Path.
+type Position struct {
+	Path   string `json:"path"`
+	Dev    uint64 `json:"dev"`
+	Inode  uint64 `json:"inode"`
+	Offset int64  `json:"offset"` // Byte offset just past the last processed line
+}
+
+// Matches reports whether the position refers to the given file identity
+func (p Position) Matches(id FileID) bool {
+	return p.Dev == id.Dev && p.Inode == id.Inode
+}
+
+// Store is a file-backed map of read positions keyed by log path
+type Store struct {
+	path      string     // "" for a memory-only store
+	flushMu   sync.Mutex // Serializes flushes, which share the temp file
+	mu        sync.Mutex
+	positions map[string]Position
+	dirty     bool
+}
+
+// NewMemory returns a store that is never written to disk. It keeps the
+// offsets of inputs restarted by a reload for the lifetime of the process.
+func NewMemory() *Store {
+	return &Store{positions: make(map[string]Position)}
+}
+
+// Open loads the store from path. A missing file yields an empty store, as
+// does a corrupt one: the error is logged and the files are read again from
+// the start rather than keeping the agent from starting.
+func Open(path string) (*Store, error) {
+	s := &Store{
+		path:      path,
+		positions: make(map[string]Position),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoints: %v", err)
+	}
+
+	var list []Position
+	if err := json.Unmarshal(data, &list); err != nil {
+		log.Printf("Ignoring corrupt checkpoints %s: %v", path, err)
+		return s, nil
+	}
+	for _, p := range list {
+		s.positions[p.Path] = p
+	}
+	return s, nil
+}
+
+// Get returns the stored position for a log path
+func (s *Store) Get(path string) (Position, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.positions[path]
+	return p, ok
+}
+
+// Set records a position; it is persisted on the next Flush
+func (s *Store) Set(p Position) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.positions[p.Path] = p
+	s.dirty = true
+}
+
+// Delete forgets the position of a log path
+func (s *Store) Delete(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.positions[path]; ok {
+		delete(s.positions, path)
+		s.dirty = true
+	}
+}
+
+// Flush writes the positions to disk if anything changed. The file is
+// synced and replaced atomically so a crash never leaves a half-written
+// store. On failure the store stays dirty and the next Flush retries.
+func (s *Store) Flush() error {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	s.mu.Lock()
+	if !s.dirty || s.path == "" {
+		s.mu.Unlock()
+		return nil
+	}
+	list := make([]Position, 0, len(s.positions))
+	for _, p := range s.positions {
+		list = append(list, p)
+	}
+	s.dirty = false
+	s.mu.Unlock()
+
+	if err := s.write(list); err != nil {
+		s.mu.Lock()
+		s.dirty = true
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (s *Store) write(list []Position) error {
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create checkpoint dir: %v", err)
+	}
+	tmp := s.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to write checkpoints: %v", err)
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write checkpoints: %v", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to write checkpoints: %v", err)
+	}
+	return nil
+}
+
+// Start flushes the store every interval
+func (s *Store) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := s.Flush(); err != nil {
+				log.Printf("Checkpoint flush failed: %v", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,130 @@
+package checkpoint
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string // "" for a missing file
+		want    int    // Positions loaded
+	}{
+		{"Missing file", "", 0},
+		{"Valid file", `[{"path":"/var/log/a.log","dev":1,"inode":2,"offset":3}]`, 1},
+		{"Corrupt file", `[{"path":`, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".json")
+			if tt.content != "" {
+				if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			s, err := Open(path)
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			if got := len(s.positions); got != tt.want {
+				t.Errorf("got %d positions; want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStoreFlush(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "checkpoints.json")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := Position{Path: "/var/log/a.log", Dev: 1, Inode: 2, Offset: 100}
+	s.Set(a)
+	s.Set(Position{Path: "/var/log/b.log", Offset: 5})
+	s.Delete("/var/log/b.log")
+	if got, ok := s.Get(a.Path); !ok || got != a {
+		t.Errorf("Get: got %+v, %v; want %+v", got, ok, a)
+	}
+	if err := s.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	s, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := s.Get(a.Path); !ok || got != a {
+		t.Errorf("after reopen: got %+v, %v; want %+v", got, ok, a)
+	}
+	if _, ok := s.Get("/var/log/b.log"); ok {
+		t.Error("deleted position persisted")
+	}
+}
+
+func TestStoreFlushFailureKeepsDirty(t *testing.T) {
+	dir := t.TempDir()
+	blocker := filepath.Join(dir, "file")
+	if err := os.WriteFile(blocker, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := &Store{
+		path:      filepath.Join(blocker, "checkpoints.json"), // Parent is not a directory
+		positions: make(map[string]Position),
+	}
+	s.Set(Position{Path: "/var/log/a.log", Offset: 1})
+	if err := s.Flush(); err == nil {
+		t.Fatal("Flush: expected an error")
+	}
+
+	// Once the path is writable the retained changes are written
+	s.path = filepath.Join(dir, "checkpoints.json")
+	if err := s.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if _, err := os.Stat(s.path); err != nil {
+		t.Errorf("checkpoints not written: %v", err)
+	}
+}
+
+func TestFindRotated(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+	for _, name := range []string{"access.log", "access.log.1", "access.log.2.gz", "other.log"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	position := func(name string) Position {
+		id, err := Identify(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return Position{Path: path, Dev: id.Dev, Inode: id.Inode}
+	}
+
+	tests := []struct {
+		name string
+		pos  Position
+		want string
+	}{
+		{"Rotated sibling", position("access.log.1"), filepath.Join(dir, "access.log.1")},
+		{"Compressed sibling skipped", position("access.log.2.gz"), ""},
+		{"Live file not a rotation", position("access.log"), ""},
+		{"Other file", position("other.log"), ""},
+		{"Unknown inode", Position{Path: path}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FindRotated(path, tt.pos); got != tt.want {
+				t.Errorf("FindRotated() = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"log-sentry/internal/enricher"
 	"log-sentry/internal/parser"
 	"strconv"
+	"time"
 
 	"log-sentry/internal/intelligence"
 
@@ -15,22 +16,64 @@ import (
 type LogCollector struct {
 	// Web Metrics (Generic for Nginx, Apache, etc.)
 	WebRequests      *prometheus.CounterVec
-	WebRequestBytes  *prometheus.CounterVec // often missing in standard logs
+	WebRequestBytes  *prometheus.CounterVec // only when the log format records it
 	WebResponseBytes *prometheus.CounterVec
 	WebAttacks       *prometheus.CounterVec
 	WebAnomalies     *prometheus.CounterVec
 	WebLatency       *prometheus.HistogramVec // NEW: Latency Histogram
+	WebFileRequests  *prometheus.CounterVec   // Per log file / vhost breakdown
+	WebUpstreamTime  *prometheus.HistogramVec // Time spent waiting on backends
+
+	// Proxy specifics
+	EnvoyResponseFlags *prometheus.CounterVec
+	HAProxyTermination *prometheus.CounterVec
+	HAProxyDuration    *prometheus.HistogramVec // Per backend/server and timer
+	TraefikRequests    *prometheus.CounterVec
+	TraefikDuration    *prometheus.HistogramVec
+	IngressRequests    *prometheus.CounterVec // ingress-nginx, per Kubernetes ingress/service
 
 	// User Agent Metric
 	WebClientType    *prometheus.CounterVec
 	
 	// Parser Health
 	ParserErrors     *prometheus.CounterVec
+	ParserPatterns   *prometheus.CounterVec // Custom grok parsers, per pattern tried
+	LogFormat        *prometheus.GaugeVec // Format detected per file (parser: auto)
+
+	// Web Server Error Logs
+	WebServerErrors  *prometheus.CounterVec
+
+	// Application Logs
+	AppExceptions    *prometheus.CounterVec
 	
 	// SSH Metrics
 	SSHLoginAttempts  *prometheus.CounterVec
 	SSHDisconnects    *prometheus.CounterVec
 	SSHActiveSessions prometheus.Gauge
+	SSHEvents         *prometheus.CounterVec   // Pre-authentication events
+	SSHSessionTime    *prometheus.HistogramVec // Per user
+	SSHKeyLogins      *prometheus.CounterVec   // Per public key fingerprint
+	SSHSessionsLost   *prometheus.CounterVec   // Sessions whose end was not seen
+
+	sshSessions *parser.SSHSessions
+
+	// sudo/su Metrics
+	PrivilegeEscalations *prometheus.CounterVec
+	HighRiskCommands     *prometheus.CounterVec // sudo commands matching an analyzer rule
+
+	// auditd Metrics
+	AuditExecs          *prometheus.CounterVec // By login users
+	AuditWatchEvents    *prometheus.CounterVec // Per rule key
+	AuditFailedSyscalls *prometheus.CounterVec
+
+	// Firewall Metrics
+	FirewallPackets   *prometheus.CounterVec
+	FirewallPortScans *prometheus.CounterVec
+
+	// Mail Metrics (Postfix, Dovecot)
+	MailAuthAttempts *prometheus.CounterVec
+	MailRejects      *prometheus.CounterVec
+	MailAnomalies    *prometheus.CounterVec
 	
 	// Enricher
 	Enricher         *enricher.Enricher
@@ -51,7 +94,7 @@ func NewLogCollector(enricher *enricher.Enricher) *LogCollector {
 		WebRequestBytes: prometheus.NewCounterVec(
 			prometheus.CounterOpts{
 				Name: "http_request_bytes_total",
-				Help: "Total number of bytes received (estimated).",
+				Help: "Total number of bytes received, for log formats that record it.",
 			},
 			[]string{"service", "method"},
 		),
@@ -70,6 +113,21 @@ func NewLogCollector(enricher *enricher.Enricher) *LogCollector {
 			},
 			[]string{"service", "method", "path"},
 		),
+		WebUpstreamTime: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "http_upstream_duration_seconds",
+				Help:    "Histogram of time spent waiting on upstream servers, per vhost.",
+				Buckets: prometheus.DefBuckets,
+			},
+			[]string{"service", "vhost"},
+		),
+		WebFileRequests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "http_requests_by_file_total",
+				Help: "Total number of HTTP requests per source log file and vhost.",
+			},
+			[]string{"service", "log_path", "vhost", "status"},
+		),
 		WebAttacks: prometheus.NewCounterVec(
 			prometheus.CounterOpts{
 				Name: "web_attack_detected_total",
@@ -98,6 +156,78 @@ func NewLogCollector(enricher *enricher.Enricher) *LogCollector {
 			},
 			[]string{"service", "reason"},
 		),
+		ParserPatterns: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "log_parser_pattern_results_total",
+				Help: "Lines matched (result=match) or not (result=miss) by each pattern tried of custom grok parsers.",
+			},
+			[]string{"service", "parser", "pattern", "result"},
+		),
+		EnvoyResponseFlags: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "envoy_response_flags_total",
+				Help: "Total number of Envoy requests by response flag (UH, UF, UO, NR, URX, DC, ...).",
+			},
+			[]string{"service", "flag"},
+		),
+		HAProxyTermination: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "haproxy_termination_state_total",
+				Help: "Total number of HAProxy sessions by termination state (first two characters, e.g. sD server timeout, cD client abort).",
+			},
+			[]string{"service", "backend", "state"},
+		),
+		HAProxyDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "haproxy_backend_duration_seconds",
+				Help:    "Histogram of HAProxy timers (queue, connect, response, total) per backend and server.",
+				Buckets: prometheus.DefBuckets,
+			},
+			[]string{"service", "backend", "server", "phase"},
+		),
+		TraefikRequests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "traefik_router_requests_total",
+				Help: "Total number of Traefik requests per router and Traefik service (backend).",
+			},
+			[]string{"service", "router", "backend", "status"},
+		),
+		TraefikDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "traefik_router_duration_seconds",
+				Help:    "Histogram of Traefik request duration per router and Traefik service (backend).",
+				Buckets: prometheus.DefBuckets,
+			},
+			[]string{"service", "router", "backend"},
+		),
+		IngressRequests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "ingress_nginx_requests_total",
+				Help: "Total number of ingress-nginx requests per namespace, ingress, Kubernetes service (backend) and upstream.",
+			},
+			[]string{"service", "namespace", "ingress", "backend", "upstream", "status"},
+		),
+		LogFormat: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "log_format_detected",
+				Help: "Log format detected for each auto-detected file (1 for the current format).",
+			},
+			[]string{"service", "path", "format"},
+		),
+		WebServerErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "web_server_errors_total",
+				Help: "Total number of web server error log events by level and category.",
+			},
+			[]string{"service", "level", "category"},
+		),
+		AppExceptions: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "app_exceptions_total",
+				Help: "Total number of exceptions (stack traces) in application logs, by exception class.",
+			},
+			[]string{"service", "exception"},
+		),
 		SSHLoginAttempts: prometheus.NewCounterVec(
 			prometheus.CounterOpts{
 				Name: "ssh_login_attempts_total",
@@ -115,8 +245,108 @@ func NewLogCollector(enricher *enricher.Enricher) *LogCollector {
 		SSHActiveSessions: prometheus.NewGauge(
 			prometheus.GaugeOpts{
 				Name: "ssh_active_sessions",
-				Help: "Estimated number of active SSH sessions.",
+				Help: "Number of active SSH sessions, tracked from login to logout.",
+			},
+		),
+		SSHEvents: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "ssh_events_total",
+				Help: "Total number of SSH connection events (invalid_user, max_auth_attempts, preauth_disconnect, connection_closed).",
+			},
+			[]string{"event"},
+		),
+		SSHSessionTime: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "ssh_session_duration_seconds",
+				Help:    "Histogram of SSH session durations.",
+				Buckets: prometheus.ExponentialBuckets(10, 4, 8), // 10s to ~45h
+			},
+			[]string{"user"},
+		),
+		SSHKeyLogins: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "ssh_key_logins_total",
+				Help: "Total number of successful SSH public key logins, by key fingerprint.",
+			},
+			[]string{"user", "key_type", "fingerprint"},
+		),
+		SSHSessionsLost: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "ssh_sessions_lost_total",
+				Help: "Total number of SSH sessions dropped without their end being logged (replaced: sshd PID reused, expired: older than ssh.session_max_age).",
+			},
+			[]string{"reason"},
+		),
+		sshSessions: parser.NewSSHSessions(24 * time.Hour), // Until SetSSHSessionMaxAge
+		PrivilegeEscalations: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "privilege_escalation_total",
+				Help: "Total number of sudo, su and PAM privilege escalation attempts.",
+			},
+			[]string{"user", "target_user", "result"},
+		),
+		HighRiskCommands: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "privilege_high_risk_commands_total",
+				Help: "Total number of commands run through sudo matching a high-risk rule (shell, setuid, pipe_to_shell, ...).",
+			},
+			[]string{"user", "target_user", "rule"},
+		),
+		AuditExecs: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "audit_execs_total",
+				Help: "Total number of programs executed by regular (non-system) users, from the audit log.",
+			},
+			[]string{"user", "exe"},
+		),
+		AuditWatchEvents: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "audit_watch_events_total",
+				Help: "Total number of audit events matching a rule or watch, by its key.",
+			},
+			[]string{"key", "success"},
+		),
+		AuditFailedSyscalls: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "audit_failed_syscalls_total",
+				Help: "Total number of failed audited syscalls, by syscall and exit code.",
+			},
+			[]string{"syscall", "exit"},
+		),
+		FirewallPackets: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "firewall_packets_total",
+				Help: "Total number of packets logged by the firewall (iptables, nftables, UFW).",
 			},
+			[]string{"action", "proto", "dst_port", "network_type"},
+		),
+		FirewallPortScans: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "firewall_port_scans_total",
+				Help: "Total number of port scans (one source hitting many distinct ports) seen in firewall logs.",
+			},
+			[]string{"source_ip", "network_type"},
+		),
+		MailAuthAttempts: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "mail_auth_attempts_total",
+				Help: "Total number of SMTP/IMAP/POP3 login attempts.",
+			},
+			[]string{"service", "user", "ip", "result"},
+		),
+		MailRejects: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "mail_rejects_total",
+				Help: "Total number of messages rejected by Postfix before queueing.",
+			},
+			[]string{"reason"},
+		),
+		MailAnomalies: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "mail_anomaly_detected_total",
+				Help: "Total number of failed logins above the per-IP brute-force threshold.",
+			},
+			[]string{"service", "type", "source_ip"},
 		),
 	}
 }
@@ -127,13 +357,39 @@ func (c *LogCollector) Register(reg prometheus.Registerer) {
 		c.WebRequestBytes,
 		c.WebResponseBytes,
 		c.WebLatency, // NEW
+		c.WebFileRequests,
+		c.WebUpstreamTime,
+		c.EnvoyResponseFlags,
+		c.HAProxyTermination,
+		c.HAProxyDuration,
+		c.TraefikRequests,
+		c.TraefikDuration,
+		c.IngressRequests,
 		c.WebAttacks,
 		c.WebAnomalies,
 		c.WebClientType, // NEW
 		c.ParserErrors,  // NEW
+		c.ParserPatterns,
+		c.LogFormat,
+		c.WebServerErrors,
+		c.AppExceptions,
 		c.SSHLoginAttempts,
 		c.SSHDisconnects,
 		c.SSHActiveSessions,
+		c.SSHEvents,
+		c.SSHSessionTime,
+		c.SSHKeyLogins,
+		c.SSHSessionsLost,
+		c.PrivilegeEscalations,
+		c.HighRiskCommands,
+		c.AuditExecs,
+		c.AuditWatchEvents,
+		c.AuditFailedSyscalls,
+		c.FirewallPackets,
+		c.FirewallPortScans,
+		c.MailAuthAttempts,
+		c.MailRejects,
+		c.MailAnomalies,
 	)
 }
 
@@ -161,7 +417,7 @@ func (c *LogCollector) ProcessWeb(entry *parser.GenericLogEntry, attack analyzer
 	c.WebRequestBytes.WithLabelValues(
 		entry.Service,
 		entry.Method,
-	).Inc() // Using Inc() as a simple counter for now, request bytes is hard to know exactly without header parsing
+	).Add(float64(entry.RequestBytes)) // 0 unless the format logs it ($request_length, %I, ...)
 
 	c.WebResponseBytes.WithLabelValues(
 		entry.Service,
@@ -211,14 +467,204 @@ func (c *LogCollector) ProcessWeb(entry *parser.GenericLogEntry, attack analyzer
 	}
 }
 
-func (c *LogCollector) ProcessSSH(entry *parser.SSHLogEntry) {
-	if entry.Type == parser.SSHLoginSuccess {
+// ProcessSource records which file (and vhost) a request was read from.
+// Without a vhost taken from the file path, the logged Host is used.
+func (c *LogCollector) ProcessSource(entry *parser.GenericLogEntry, logPath, vhost string) {
+	if vhost == "" {
+		vhost = entry.Host
+	}
+	if entry.UpstreamLatency > 0 {
+		c.WebUpstreamTime.WithLabelValues(entry.Service, vhost).Observe(entry.UpstreamLatency)
+	}
+	if logPath == "" {
+		return
+	}
+	c.WebFileRequests.WithLabelValues(
+		entry.Service,
+		logPath,
+		vhost,
+		strconv.Itoa(entry.Status),
+	).Inc()
+}
+
+// haproxyPhases maps the HAProxy timer extras to the phase label
+var haproxyPhases = []struct{ key, phase string }{
+	{"time_queue_ms", "queue"},
+	{"time_connect_ms", "connect"},
+	{"time_response_ms", "response"},
+	{"time_total_ms", "total"},
+}
+
+// ProcessProxy records the proxy-specific details of an entry
+func (c *LogCollector) ProcessProxy(entry *parser.GenericLogEntry) {
+	for _, flag := range parser.EnvoyResponseFlags(entry.Extra.Get("response_flags")) {
+		c.EnvoyResponseFlags.WithLabelValues(entry.Service, flag).Inc()
+	}
+
+	if state := entry.Extra.Get("termination_state"); len(state) >= 2 {
+		backend, server := entry.Extra.Get("backend"), entry.Extra.Get("server")
+		c.HAProxyTermination.WithLabelValues(entry.Service, backend, state[:2]).Inc()
+		for _, t := range haproxyPhases {
+			// -1 (phase not reached) and missing timers are skipped
+			if ms, err := strconv.Atoi(entry.Extra.Get(t.key)); err == nil && ms >= 0 {
+				c.HAProxyDuration.WithLabelValues(entry.Service, backend, server, t.phase).Observe(float64(ms) / 1000)
+			}
+		}
+	}
+
+	if router := entry.Extra.Get("router_name"); router != "" {
+		backend := entry.Extra.Get("service_name")
+		c.TraefikRequests.WithLabelValues(entry.Service, router, backend, strconv.Itoa(entry.Status)).Inc()
+		c.TraefikDuration.WithLabelValues(entry.Service, router, backend).Observe(entry.Latency)
+	}
+
+	if upstream := entry.Extra.Get("proxy_upstream_name"); upstream != "" {
+		c.IngressRequests.WithLabelValues(entry.Service, entry.Extra.Get("namespace"), entry.Extra.Get("ingress_name"),
+			entry.Extra.Get("service_name"), upstream, strconv.Itoa(entry.Status)).Inc()
+	}
+}
+
+// SetLogFormat records the format detected for a file, replacing the
+// previous one (if any)
+func (c *LogCollector) SetLogFormat(service, path, previous, format string) {
+	if previous != "" {
+		c.LogFormat.DeleteLabelValues(service, path, previous)
+	}
+	c.LogFormat.WithLabelValues(service, path, format).Set(1)
+}
+
+// ProcessError records an nginx/Apache error log event
+func (c *LogCollector) ProcessError(service string, entry *parser.ErrorLogEntry) {
+	c.WebServerErrors.WithLabelValues(service, entry.Level, string(entry.Category)).Inc()
+}
+
+// ProcessApp records an application log event (possibly multi-line)
+func (c *LogCollector) ProcessApp(service, event string) {
+	if exception := parser.ExceptionClass(event); exception != "" {
+		c.AppExceptions.WithLabelValues(service, exception).Inc()
+	}
+}
+
+// ProcessSSH updates the SSH metrics; sessions are tracked per service
+func (c *LogCollector) ProcessSSH(service string, entry *parser.SSHLogEntry) {
+	switch entry.Type {
+	case parser.SSHLoginSuccess:
 		c.SSHLoginAttempts.WithLabelValues(entry.User, entry.IP, "success", entry.AuthMethod).Inc()
-		c.SSHActiveSessions.Inc()
-	} else if entry.Type == parser.SSHLoginFailed {
+		if entry.Fingerprint != "" {
+			c.SSHKeyLogins.WithLabelValues(entry.User, entry.KeyType, entry.Fingerprint).Inc()
+		}
+		c.startSSHSession(service, entry)
+	case parser.SSHSessionOpened:
+		c.startSSHSession(service, entry)
+	case parser.SSHLoginFailed:
 		c.SSHLoginAttempts.WithLabelValues(entry.User, entry.IP, "failed", entry.AuthMethod).Inc()
-	} else if entry.Type == parser.SSHDisconnect {
+	case parser.SSHInvalidUser:
+		c.SSHEvents.WithLabelValues("invalid_user").Inc()
+	case parser.SSHMaxAuthAttempts:
+		c.SSHEvents.WithLabelValues("max_auth_attempts").Inc()
+	case parser.SSHConnectionClosed:
+		c.SSHEvents.WithLabelValues("connection_closed").Inc()
+	case parser.SSHDisconnect:
 		c.SSHDisconnects.WithLabelValues().Inc()
-		c.SSHActiveSessions.Dec()
+		if entry.Preauth {
+			c.SSHEvents.WithLabelValues("preauth_disconnect").Inc()
+			break
+		}
+		c.endSSHSession(service, entry)
+	case parser.SSHSessionClosed:
+		c.endSSHSession(service, entry)
+	}
+	now := entry.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+	for range c.sshSessions.Expire(now) {
+		c.SSHSessionsLost.WithLabelValues("expired").Inc()
+	}
+	c.SSHActiveSessions.Set(float64(c.sshSessions.Active()))
+}
+
+// SetSSHSessionMaxAge sets how long an SSH session is tracked without its
+// end being logged
+func (c *LogCollector) SetSSHSessionMaxAge(maxAge time.Duration) {
+	c.sshSessions.SetMaxAge(maxAge)
+}
+
+func (c *LogCollector) startSSHSession(service string, entry *parser.SSHLogEntry) {
+	if _, replaced := c.sshSessions.Start(service, entry); replaced {
+		c.SSHSessionsLost.WithLabelValues("replaced").Inc()
+	}
+}
+
+func (c *LogCollector) endSSHSession(service string, entry *parser.SSHLogEntry) {
+	if session, d, ok := c.sshSessions.End(service, entry); ok {
+		c.SSHSessionTime.WithLabelValues(session.User).Observe(d.Seconds())
+	}
+}
+
+// ProcessPrivilege updates the sudo/su metrics. rule is the high-risk
+// command rule matched by event.Command, "" if none.
+func (c *LogCollector) ProcessPrivilege(event *parser.PrivilegeEvent, rule string) {
+	c.PrivilegeEscalations.WithLabelValues(event.User, event.TargetUser, event.Result).Inc()
+	if rule != "" {
+		c.HighRiskCommands.WithLabelValues(event.User, event.TargetUser, rule).Inc()
+	}
+}
+
+// ProcessAudit updates the auditd metrics with a reassembled event
+func (c *LogCollector) ProcessAudit(event *parser.AuditEvent) {
+	if event.Exec() && event.LoginUser() {
+		exe := event.Exe
+		if exe == "" {
+			exe = event.Argv[0]
+		}
+		c.AuditExecs.WithLabelValues(event.User, exe).Inc()
+	}
+	for _, key := range event.Keys {
+		c.AuditWatchEvents.WithLabelValues(key, strconv.FormatBool(event.Success)).Inc()
+	}
+	if event.Syscall != "" && !event.Success {
+		c.AuditFailedSyscalls.WithLabelValues(event.Syscall, event.Exit).Inc()
+	}
+}
+
+// ProcessFirewall updates the firewall metrics; scan is set when the packet
+// completed a port scan
+func (c *LogCollector) ProcessFirewall(entry *parser.FirewallEntry, scan bool) {
+	networkType := "unknown"
+	if c.Enricher != nil {
+		networkType = c.Enricher.ClassifyIP(entry.SrcIP)
+	}
+	c.FirewallPackets.WithLabelValues(entry.Action, entry.Proto, firewallPort(entry.DstPort), networkType).Inc()
+	if scan {
+		c.FirewallPortScans.WithLabelValues(entry.SrcIP, networkType).Inc()
+	}
+}
+
+// firewallPort is the dst_port label. Ports of the dynamic range (49152+),
+// which scans and replies to outgoing connections spread over, share one.
+func firewallPort(port int) string {
+	switch {
+	case port == 0:
+		return ""
+	case port >= 49152:
+		return "dynamic"
+	}
+	return strconv.Itoa(port)
+}
+
+// ProcessMail updates the mail metrics; anomalyType is the brute-force
+// detection result for failed logins
+func (c *LogCollector) ProcessMail(entry *parser.MailLogEntry, anomalyType anomaly.AnomalyType) {
+	switch entry.Type {
+	case parser.MailAuthSuccess:
+		c.MailAuthAttempts.WithLabelValues(entry.Service, entry.User, entry.IP, "success").Inc()
+	case parser.MailAuthFailed:
+		c.MailAuthAttempts.WithLabelValues(entry.Service, entry.User, entry.IP, "failed").Add(float64(entry.Attempts))
+	case parser.MailReject:
+		c.MailRejects.WithLabelValues(entry.Reason).Inc()
+	}
+	if anomalyType != "" {
+		c.MailAnomalies.WithLabelValues(entry.Service, string(anomalyType), entry.IP).Inc()
 	}
 }
@@ -1,32 +1,576 @@
 package config
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"regexp"
 	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
+// DefaultConfigPath is used when neither -config nor LOG_SENTRY_CONFIG is set.
+// A missing file at this path is not an error; defaults + env are used instead.
+const DefaultConfigPath = "/etc/log-sentry/config.yaml"
+
+// Config is the full agent configuration. It is loaded from an optional YAML
+// file, then overridden by the legacy environment variables.
 type Config struct {
-	NginxAccessLogPath string
-	NginxErrorLogPath  string
-	SSHAuthLogPath     string
-	Port               int
-	EnableMagicLogAccess bool
-	EnableCrowdSec       bool
-	CrowdSecLAPIURL      string
-	CrowdSecAPIKey       string
+	// Legacy single-path settings (still honoured, and overridable via env)
+	NginxAccessLogPath   string `yaml:"nginx_access_log_path"`
+	NginxErrorLogPath    string `yaml:"nginx_error_log_path"`
+	SSHAuthLogPath       string `yaml:"ssh_auth_log_path"`
+	EnableMagicLogAccess bool   `yaml:"enable_magic_log_access"`
+
+	Workers    int              `yaml:"workers"`
+	Discovery  DiscoveryConfig  `yaml:"discovery"`
+	Syslog     SyslogConfig     `yaml:"syslog"`
+	Inputs     []InputConfig    `yaml:"inputs"`
+	Parsers    []ParserConfig   `yaml:"parsers"`
+	Checkpoint CheckpointConfig `yaml:"checkpoint"`
+	Backfill   BackfillConfig   `yaml:"backfill"`
+	Monitors   MonitorsConfig   `yaml:"monitors"`
+	Anomaly    AnomalyConfig    `yaml:"anomaly"`
+	SSH        SSHConfig        `yaml:"ssh"`
+	Analyzer   AnalyzerConfig   `yaml:"analyzer"`
+	CrowdSec   CrowdSecConfig   `yaml:"crowdsec"`
+	Outputs    OutputsConfig    `yaml:"outputs"`
+	Admin      AdminConfig      `yaml:"admin"`
+}
+
+type DiscoveryConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+type SyslogConfig struct {
+	Enabled bool `yaml:"enabled"`
+	Port    int  `yaml:"port"`
+}
+
+// Input types understood by the ingestion pipeline
+const (
+	InputAccess   = "access"   // Web access log, parsed into GenericLogEntry
+	InputError    = "error"    // nginx error.log / Apache error_log
+	InputSSH      = "ssh"      // Auth log: OpenSSH, sudo, su
+	InputApp      = "app"      // Application log (catalina.out, ...), counted by exception class
+	InputAudit    = "audit"    // Linux audit log (/var/log/audit/audit.log)
+	InputFirewall = "firewall" // iptables/nftables/UFW packet logs (kern.log, ufw.log)
+	InputMail     = "mail"     // Postfix/Dovecot mail log (mail.log, maillog)
+)
+
+// InputConfig declares one tailed log source. Path may be a file, a
+// directory or a glob pattern (e.g. /var/log/nginx/*access*.log).
+type InputConfig struct {
+	Service string `yaml:"service"` // May contain {vhost}
+	Type    string `yaml:"type"`    // One of the Input* types, access by default
+	Path    string `yaml:"path"`
+	Parser  string `yaml:"parser"` // Parser name, e.g. "nginx", "json" or "auto" to detect (access inputs only)
+
+	// VhostPattern is a regex applied to each matched file path; its "vhost"
+	// group (or first group) becomes the vhost label.
+	VhostPattern string `yaml:"vhost_pattern"`
+
+	// Custom log format for the parser (an nginx log_format or Apache
+	// LogFormat string), or the name of one defined in the server's config
+	// file (ServerConfig, defaulting to the usual location such as
+	// /etc/nginx/nginx.conf).
+	LogFormat     string `yaml:"log_format"`
+	LogFormatName string `yaml:"log_format_name"`
+	ServerConfig  string `yaml:"server_config"`
+
+	Multiline *MultilineConfig `yaml:"multiline"`
+
+	// Envelope strips a container runtime wrapper (cri, docker or auto)
+	// from each line before anything else, joining partial lines
+	Envelope string `yaml:"envelope"`
+}
+
+// Container runtime log envelopes
+const (
+	EnvelopeCRI    = "cri"    // containerd/CRI-O: "<time> <stream> <P|F> <line>"
+	EnvelopeDocker = "docker" // Docker json-file: {"log":"<line>\n","stream":"stdout","time":"..."}
+	EnvelopeAuto   = "auto"   // Either, recognized per line
+)
+
+// MultilineConfig joins continuation lines (e.g. Java stack traces) into a
+// single event. Lines matching StartPattern begin a new event; with only
+// ContinuePattern, lines matching it are appended to the previous one.
+type MultilineConfig struct {
+	StartPattern    string        `yaml:"start_pattern"`
+	ContinuePattern string        `yaml:"continue_pattern"`
+	MaxLines        int           `yaml:"max_lines"`     // Further lines are dropped
+	FlushTimeout    time.Duration `yaml:"flush_timeout"` // Emit the last event after this long without new lines
 }
 
-func Load() *Config {
+// Custom parser types
+const (
+	ParserGrok   = "grok"
+	ParserJSON   = "json"
+	ParserLogfmt = "logfmt"
+)
+
+// ParserConfig defines a custom parser, usable by name as the parser of
+// access inputs. Grok patterns are compiled once, when the config is applied.
+type ParserConfig struct {
+	Name string `yaml:"name"`
+	Type string `yaml:"type"` // grok (default), json or logfmt
+
+	// Patterns are tried in order: grok expressions
+	// ("%{IPORHOST:client} %{HTTPDATE:time} ...") or regexes with named
+	// groups. PatternDefinitions adds named patterns to the standard library.
+	Patterns           []string          `yaml:"patterns"`
+	PatternDefinitions map[string]string `yaml:"pattern_definitions"`
+
+	// Fields maps captured names (grok), keys (logfmt) or dotted paths such
+	// as "request.headers.User-Agent[0]" (json) onto entry fields
+	// (remote_ip, status, time, latency, latency_ms, ...). Unmapped grok
+	// captures and logfmt keys are kept as extra fields; unmapped JSON
+	// values are ignored.
+	Fields     map[string]string `yaml:"fields"`
+	TimeLayout string            `yaml:"time_layout"` // Go layout, or unix, unix_ms, unix_us, unix_ns
+}
+
+// CheckpointConfig controls persistence of per-file read offsets
+type CheckpointConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	Path          string        `yaml:"path"`
+	FlushInterval time.Duration `yaml:"flush_interval"`
+}
+
+// BackfillConfig enables reading rotated logs (access.log.1,
+// access.log.2.gz, ...) of newly seen files before tailing them
+type BackfillConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+type MonitorsConfig struct {
+	SSL      SSLMonitorConfig     `yaml:"ssl"`
+	FIM      FIMConfig            `yaml:"fim"`
+	Process  ProcessMonitorConfig `yaml:"process"`
+	Journald JournaldConfig       `yaml:"journald"`
+}
+
+type SSLMonitorConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	Interval time.Duration `yaml:"interval"`
+	Targets  []string      `yaml:"targets"`
+}
+
+type FIMConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	Interval time.Duration `yaml:"interval"`
+	Paths    []string      `yaml:"paths"`
+}
+
+type ProcessMonitorConfig struct {
+	Enabled   bool          `yaml:"enabled"`
+	Interval  time.Duration `yaml:"interval"`
+	Blacklist []string      `yaml:"blacklist"`
+}
+
+type JournaldConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+type AnomalyConfig struct {
+	Threshold404 int           `yaml:"threshold_404"`
+	Threshold500 int           `yaml:"threshold_500"`
+	Window       time.Duration `yaml:"window"`
+
+	// Failed logins from one IP within the window (mail inputs)
+	ThresholdAuthFailures int `yaml:"threshold_auth_failures"`
+
+	// Firewall inputs: a source hitting this many distinct ports within
+	// the window is a port scan
+	PortScanPorts  int           `yaml:"port_scan_ports"`
+	PortScanWindow time.Duration `yaml:"port_scan_window"`
+}
+
+// SSHConfig tunes the SSH session tracking of ssh inputs
+type SSHConfig struct {
+	// SessionMaxAge drops sessions whose end was never logged (sshd killed,
+	// lines lost) once they are older than this
+	SessionMaxAge time.Duration `yaml:"session_max_age"`
+}
+
+// AnalyzerConfig overrides the attack detection rules. Empty patterns keep
+// the built-in ones.
+type AnalyzerConfig struct {
+	SQLi              string `yaml:"sqli"`
+	XSS               string `yaml:"xss"`
+	PathTraversal     string `yaml:"path_traversal"`
+	Scanner           string `yaml:"scanner"`
+	ExfiltrationBytes int    `yaml:"exfiltration_bytes"`
+
+	// Rule name -> pattern of sudo commands to flag; replaces the built-in
+	// shell, setuid and pipe_to_shell rules
+	HighRiskCommands map[string]string `yaml:"high_risk_commands"`
+}
+
+type CrowdSecConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	LAPIURL string `yaml:"lapi_url"`
+	APIKey  string `yaml:"api_key"`
+}
+
+type OutputsConfig struct {
+	Prometheus PrometheusOutputConfig `yaml:"prometheus"`
+}
+
+type PrometheusOutputConfig struct {
+	Port int    `yaml:"port"`
+	Path string `yaml:"path"`
+}
+
+type AdminConfig struct {
+	// EnableReload exposes POST /-/reload on the metrics port
+	EnableReload bool `yaml:"enable_reload"`
+}
+
+// Default returns the built-in configuration, matching the historical
+// hard-coded behaviour of the agent.
+func Default() *Config {
 	return &Config{
-		NginxAccessLogPath: getEnv("NGINX_ACCESS_LOG_PATH", "/var/log/nginx/access.log"),
-		NginxErrorLogPath:  getEnv("NGINX_ERROR_LOG_PATH", "/var/log/nginx/error.log"),
-		SSHAuthLogPath:     getEnv("SSH_AUTH_LOG_PATH", "/var/log/auth.log"),
-		Port:               getEnvInt("PORT", 9102),
-		EnableMagicLogAccess: getEnvBool("ENABLE_MAGIC_LOG_ACCESS", false),
-		EnableCrowdSec:       getEnvBool("ENABLE_CROWDSEC", false),
-		CrowdSecLAPIURL:      getEnv("CROWDSEC_LAPI_URL", "http://localhost:8080/"),
-		CrowdSecAPIKey:       getEnv("CROWDSEC_API_KEY", ""),
+		NginxAccessLogPath: "/var/log/nginx/access.log",
+		NginxErrorLogPath:  "/var/log/nginx/error.log",
+		SSHAuthLogPath:     "/var/log/auth.log",
+		Workers:            5,
+		Discovery:          DiscoveryConfig{Enabled: true},
+		Syslog:             SyslogConfig{Enabled: true, Port: 5140},
+		Checkpoint: CheckpointConfig{
+			Enabled:       true,
+			Path:          "/var/lib/log-sentry/checkpoints.json",
+			FlushInterval: 10 * time.Second,
+		},
+		Monitors: MonitorsConfig{
+			SSL: SSLMonitorConfig{
+				Enabled:  true,
+				Interval: 1 * time.Hour,
+				Targets:  []string{"localhost:443"},
+			},
+			FIM: FIMConfig{
+				Enabled:  true,
+				Interval: 30 * time.Second,
+				Paths:    []string{"/etc/passwd"},
+			},
+			Process: ProcessMonitorConfig{
+				Enabled:   true,
+				Interval:  30 * time.Second,
+				Blacklist: []string{"nc", "nmap", "hydra", "john", "xmrig"},
+			},
+			Journald: JournaldConfig{Enabled: true},
+		},
+		Anomaly: AnomalyConfig{
+			Threshold404: 10,
+			Threshold500: 20,
+			Window:       1 * time.Minute,
+
+			ThresholdAuthFailures: 10,
+
+			PortScanPorts:  20,
+			PortScanWindow: 1 * time.Minute,
+		},
+		SSH: SSHConfig{SessionMaxAge: 24 * time.Hour},
+		CrowdSec: CrowdSecConfig{
+			LAPIURL: "http://localhost:8080/",
+		},
+		Outputs: OutputsConfig{
+			Prometheus: PrometheusOutputConfig{Port: 9102, Path: "/metrics"},
+		},
+	}
+}
+
+// Load builds the configuration from defaults, the YAML file at path (if any)
+// and environment overrides, then validates it.
+// An empty path falls back to $LOG_SENTRY_CONFIG, then DefaultConfigPath.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	explicit := true
+	if path == "" {
+		path = os.Getenv("LOG_SENTRY_CONFIG")
+	}
+	if path == "" {
+		path = DefaultConfigPath
+		explicit = false
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := decode(data, cfg); err != nil {
+			return nil, fmt.Errorf("config %s: %w", path, err)
+		}
+	case errors.Is(err, os.ErrNotExist) && !explicit:
+		// No config file: defaults + env only
+	default:
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+
+	cfg.applyEnv()
+	cfg.normalize()
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// decode unmarshals YAML over the defaults, rejecting unknown keys so typos
+// surface at startup instead of being silently ignored.
+func decode(data []byte, cfg *Config) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil // Empty file
+		}
+		return err
+	}
+	return nil
+}
+
+// applyEnv keeps the pre-YAML environment variables working; they take
+// precedence over the file.
+func (c *Config) applyEnv() {
+	c.NginxAccessLogPath = getEnv("NGINX_ACCESS_LOG_PATH", c.NginxAccessLogPath)
+	c.NginxErrorLogPath = getEnv("NGINX_ERROR_LOG_PATH", c.NginxErrorLogPath)
+	c.SSHAuthLogPath = getEnv("SSH_AUTH_LOG_PATH", c.SSHAuthLogPath)
+	c.Outputs.Prometheus.Port = getEnvInt("PORT", c.Outputs.Prometheus.Port)
+	c.EnableMagicLogAccess = getEnvBool("ENABLE_MAGIC_LOG_ACCESS", c.EnableMagicLogAccess)
+	c.CrowdSec.Enabled = getEnvBool("ENABLE_CROWDSEC", c.CrowdSec.Enabled)
+	c.CrowdSec.LAPIURL = getEnv("CROWDSEC_LAPI_URL", c.CrowdSec.LAPIURL)
+	c.CrowdSec.APIKey = getEnv("CROWDSEC_API_KEY", c.CrowdSec.APIKey)
+	c.Workers = getEnvInt("WORKERS", c.Workers)
+	c.Syslog.Port = getEnvInt("SYSLOG_PORT", c.Syslog.Port)
+	c.Checkpoint.Path = getEnv("CHECKPOINT_PATH", c.Checkpoint.Path)
+}
+
+// normalize fills in the per-entry defaults of inputs and parsers, which
+// Default cannot provide for list items
+func (c *Config) normalize() {
+	for i := range c.Inputs {
+		in := &c.Inputs[i]
+		if in.Type == "" {
+			in.Type = InputAccess
+		}
+		if ml := in.Multiline; ml != nil {
+			if ml.MaxLines == 0 {
+				ml.MaxLines = 500
+			}
+			if ml.FlushTimeout == 0 {
+				ml.FlushTimeout = 2 * time.Second
+			}
+		}
+	}
+	for i := range c.Parsers {
+		if c.Parsers[i].Type == "" {
+			c.Parsers[i].Type = ParserGrok
+		}
+	}
+}
+
+// Validate checks the configuration and reports every problem found,
+// one per line, prefixed with the offending key. It does not modify c.
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(key, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+
+	if c.Workers < 1 {
+		fail("workers", "must be at least 1, got %d", c.Workers)
+	}
+	if c.Syslog.Enabled && !validPort(c.Syslog.Port) {
+		fail("syslog.port", "must be between 1 and 65535, got %d", c.Syslog.Port)
+	}
+	if !validPort(c.Outputs.Prometheus.Port) {
+		fail("outputs.prometheus.port", "must be between 1 and 65535, got %d", c.Outputs.Prometheus.Port)
 	}
+	if c.Outputs.Prometheus.Path == "" || c.Outputs.Prometheus.Path[0] != '/' {
+		fail("outputs.prometheus.path", "must start with '/', got %q", c.Outputs.Prometheus.Path)
+	}
+
+	seen := make(map[string]int)
+	for i, in := range c.Inputs {
+		key := fmt.Sprintf("inputs[%d]", i)
+		if in.Service == "" {
+			fail(key+".service", "is required")
+		}
+		if in.Path == "" {
+			fail(key+".path", "is required")
+		}
+		switch in.Type {
+		case "", InputAccess:
+			if in.Parser == "" {
+				fail(key+".parser", "is required for access inputs")
+			}
+			if in.LogFormat != "" && in.LogFormatName != "" {
+				fail(key+".log_format", "cannot be combined with log_format_name")
+			}
+			if in.ServerConfig != "" && in.LogFormatName == "" {
+				fail(key+".server_config", "is only used with log_format_name")
+			}
+		case InputError, InputSSH, InputApp, InputAudit, InputFirewall, InputMail:
+			if in.Parser != "" {
+				fail(key+".parser", "not supported for %s inputs", in.Type)
+			}
+			if in.LogFormat != "" || in.LogFormatName != "" {
+				fail(key+".log_format", "not supported for %s inputs", in.Type)
+			}
+		default:
+			fail(key+".type", "unknown input type %q (want %s, %s, %s, %s, %s, %s or %s)", in.Type, InputAccess, InputError, InputSSH, InputApp, InputAudit, InputFirewall, InputMail)
+		}
+		if ml := in.Multiline; ml != nil {
+			if ml.StartPattern == "" && ml.ContinuePattern == "" {
+				fail(key+".multiline", "start_pattern or continue_pattern is required")
+			}
+			for _, p := range []struct{ key, pattern string }{
+				{"start_pattern", ml.StartPattern},
+				{"continue_pattern", ml.ContinuePattern},
+			} {
+				if _, err := regexp.Compile(p.pattern); err != nil {
+					fail(key+".multiline."+p.key, "invalid regular expression: %v", err)
+				}
+			}
+			if ml.MaxLines < 0 {
+				fail(key+".multiline.max_lines", "must be positive, got %d", ml.MaxLines)
+			}
+			if ml.FlushTimeout < 0 {
+				fail(key+".multiline.flush_timeout", "must be positive, got %s", ml.FlushTimeout)
+			}
+		}
+		switch in.Envelope {
+		case "", EnvelopeCRI, EnvelopeDocker, EnvelopeAuto:
+		default:
+			fail(key+".envelope", "unknown envelope %q (want %s, %s or %s)", in.Envelope, EnvelopeCRI, EnvelopeDocker, EnvelopeAuto)
+		}
+		if in.VhostPattern != "" {
+			if re, err := regexp.Compile(in.VhostPattern); err != nil {
+				fail(key+".vhost_pattern", "invalid regular expression: %v", err)
+			} else if re.NumSubexp() == 0 {
+				fail(key+".vhost_pattern", "must contain a capture group")
+			}
+		}
+		if strings.Contains(in.Service, "{vhost}") && in.VhostPattern == "" {
+			fail(key+".service", "uses {vhost} but vhost_pattern is not set")
+		}
+		id := in.Service + "|" + in.Path
+		if prev, dup := seen[id]; dup {
+			fail(key, "duplicates inputs[%d] (service %q, path %q)", prev, in.Service, in.Path)
+		}
+		seen[id] = i
+	}
+
+	names := make(map[string]int)
+	for i, p := range c.Parsers {
+		key := fmt.Sprintf("parsers[%d]", i)
+		if p.Name == "" {
+			fail(key+".name", "is required")
+		} else if prev, dup := names[p.Name]; dup {
+			fail(key+".name", "%q already defined by parsers[%d]", p.Name, prev)
+		}
+		names[p.Name] = i
+		switch p.Type {
+		case "", ParserGrok:
+			if len(p.Patterns) == 0 {
+				fail(key+".patterns", "at least one pattern is required")
+			}
+		case ParserJSON, ParserLogfmt:
+			if p.Type == ParserJSON && len(p.Fields) == 0 {
+				fail(key+".fields", "at least one field path is required")
+			}
+			if len(p.Patterns) > 0 || len(p.PatternDefinitions) > 0 {
+				fail(key+".patterns", "not supported for %s parsers", p.Type)
+			}
+		default:
+			fail(key+".type", "unknown parser type %q (want %s, %s or %s)", p.Type, ParserGrok, ParserJSON, ParserLogfmt)
+		}
+	}
+
+	if c.Checkpoint.Enabled {
+		if c.Checkpoint.Path == "" {
+			fail("checkpoint.path", "is required when checkpointing is enabled")
+		}
+		if c.Checkpoint.FlushInterval <= 0 {
+			fail("checkpoint.flush_interval", "must be positive, got %s", c.Checkpoint.FlushInterval)
+		}
+	}
+	if c.Backfill.Enabled && !c.Checkpoint.Enabled {
+		fail("backfill.enabled", "requires checkpoint.enabled, otherwise rotated logs are re-read on every start")
+	}
+
+	m := c.Monitors
+	if m.SSL.Enabled && m.SSL.Interval <= 0 {
+		fail("monitors.ssl.interval", "must be positive, got %s", m.SSL.Interval)
+	}
+	if m.FIM.Enabled && m.FIM.Interval <= 0 {
+		fail("monitors.fim.interval", "must be positive, got %s", m.FIM.Interval)
+	}
+	if m.Process.Enabled && m.Process.Interval <= 0 {
+		fail("monitors.process.interval", "must be positive, got %s", m.Process.Interval)
+	}
+
+	if c.Anomaly.Threshold404 < 1 {
+		fail("anomaly.threshold_404", "must be at least 1, got %d", c.Anomaly.Threshold404)
+	}
+	if c.Anomaly.Threshold500 < 1 {
+		fail("anomaly.threshold_500", "must be at least 1, got %d", c.Anomaly.Threshold500)
+	}
+	if c.Anomaly.ThresholdAuthFailures < 1 {
+		fail("anomaly.threshold_auth_failures", "must be at least 1, got %d", c.Anomaly.ThresholdAuthFailures)
+	}
+	if c.Anomaly.Window <= 0 {
+		fail("anomaly.window", "must be positive, got %s", c.Anomaly.Window)
+	}
+	if c.Anomaly.PortScanPorts < 2 {
+		fail("anomaly.port_scan_ports", "must be at least 2, got %d", c.Anomaly.PortScanPorts)
+	}
+	if c.Anomaly.PortScanWindow <= 0 {
+		fail("anomaly.port_scan_window", "must be positive, got %s", c.Anomaly.PortScanWindow)
+	}
+	if c.SSH.SessionMaxAge <= 0 {
+		fail("ssh.session_max_age", "must be positive, got %s", c.SSH.SessionMaxAge)
+	}
+
+	for _, rule := range []struct{ key, pattern string }{
+		{"analyzer.sqli", c.Analyzer.SQLi},
+		{"analyzer.xss", c.Analyzer.XSS},
+		{"analyzer.path_traversal", c.Analyzer.PathTraversal},
+		{"analyzer.scanner", c.Analyzer.Scanner},
+	} {
+		if _, err := regexp.Compile(rule.pattern); err != nil {
+			fail(rule.key, "invalid regular expression: %v", err)
+		}
+	}
+	for name, pattern := range c.Analyzer.HighRiskCommands {
+		if _, err := regexp.Compile(pattern); err != nil {
+			fail("analyzer.high_risk_commands."+name, "invalid regular expression: %v", err)
+		}
+	}
+	if c.Analyzer.ExfiltrationBytes < 0 {
+		fail("analyzer.exfiltration_bytes", "must not be negative, got %d", c.Analyzer.ExfiltrationBytes)
+	}
+
+	if c.CrowdSec.Enabled {
+		if c.CrowdSec.LAPIURL == "" {
+			fail("crowdsec.lapi_url", "is required when crowdsec is enabled")
+		}
+		if c.CrowdSec.APIKey == "" {
+			fail("crowdsec.api_key", "is required when crowdsec is enabled")
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func validPort(p int) bool {
+	return p > 0 && p <= 65535
 }
 
 func getEnv(key, fallback string) string {
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{"Empty file uses defaults", "", ""},
+		{"Valid inputs", "inputs:\n  - service: shop\n    path: /tmp/a.log\n    parser: nginx\n", ""},
+		{"Unknown key", "workerz: 3\n", "field workerz not found"},
+		{"Missing parser", "inputs:\n  - service: shop\n    path: /tmp/a.log\n", "inputs[0].parser: is required"},
+		{"Bad input type", "inputs:\n  - service: x\n    type: foo\n    path: /tmp/a.log\n", `inputs[0].type: unknown input type "foo"`},
+		{"Bad threshold", "anomaly:\n  threshold_404: 0\n", "anomaly.threshold_404"},
+		{"Bad duration", "anomaly:\n  window: soon\n", "into time.Duration"},
+		{"Duplicate parser", "parsers:\n  - name: app\n    patterns: ['%{GREEDYDATA:path}']\n  - name: app\n    patterns: ['x']\n", `parsers[1].name: "app" already defined`},
+		{"Multiline without pattern", "inputs:\n  - service: app\n    type: app\n    path: /tmp/a.log\n    multiline:\n      max_lines: 10\n", "inputs[0].multiline: start_pattern or continue_pattern is required"},
+		{"Unknown envelope", "inputs:\n  - service: web\n    path: /tmp/a.log\n    parser: nginx\n    envelope: podman\n", `inputs[0].envelope: unknown envelope "podman"`},
+		{"Invalid high-risk command", "analyzer:\n  high_risk_commands:\n    shell: '(bash'\n", "analyzer.high_risk_commands.shell: invalid regular expression"},
+		{"Backfill without checkpoint", "checkpoint:\n  enabled: false\nbackfill:\n  enabled: true\n", "backfill.enabled: requires checkpoint.enabled"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(writeConfig(t, tt.body))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Load() error = %v; want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v; want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadEnvOverridesFile(t *testing.T) {
+	t.Setenv("PORT", "9200")
+	cfg, err := Load(writeConfig(t, "outputs:\n  prometheus:\n    port: 9100\nanomaly:\n  window: 5m\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Outputs.Prometheus.Port != 9200 {
+		t.Errorf("Port = %d; want 9200 from env", cfg.Outputs.Prometheus.Port)
+	}
+	if cfg.Anomaly.Window != 5*time.Minute {
+		t.Errorf("Anomaly.Window = %s; want 5m", cfg.Anomaly.Window)
+	}
+}
+
+func TestLoadFillsEntryDefaults(t *testing.T) {
+	cfg, err := Load(writeConfig(t, "inputs:\n  - service: app\n    path: /tmp/a.log\n    parser: app\n    multiline:\n      start_pattern: '^\\d'\nparsers:\n  - name: app\n    patterns: ['%{GREEDYDATA:path}']\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := cfg.Inputs[0]
+	if in.Type != InputAccess || in.Multiline.MaxLines != 500 || in.Multiline.FlushTimeout != 2*time.Second {
+		t.Errorf("input = %+v, multiline = %+v; want access defaults", in, *in.Multiline)
+	}
+	if cfg.Parsers[0].Type != ParserGrok {
+		t.Errorf("parser type = %q; want %q", cfg.Parsers[0].Type, ParserGrok)
+	}
+
+	// Validate leaves the config alone
+	raw := Default()
+	raw.Inputs = []InputConfig{{Service: "app", Path: "/tmp/a.log", Parser: "nginx", Multiline: &MultilineConfig{StartPattern: `^\d`}}}
+	if err := raw.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if raw.Inputs[0].Type != "" || raw.Inputs[0].Multiline.MaxLines != 0 {
+		t.Errorf("Validate() modified the input: %+v", raw.Inputs[0])
+	}
+}
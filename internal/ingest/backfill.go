@@ -0,0 +1,73 @@
+package ingest
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// rotatedSiblings returns the rotated copies of path (path.1, path.2.gz,
+// path-20240101.zst, ...) oldest first, by modification time.
+func rotatedSiblings(path string) []string {
+	dir, base := filepath.Split(path)
+	entries, err := os.ReadDir(filepath.Clean(dir))
+	if err != nil {
+		return nil
+	}
+
+	type rotated struct {
+		path    string
+		modTime time.Time
+	}
+	var files []rotated
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasPrefix(name, base) || len(name) == len(base) {
+			continue
+		}
+		// Only path.<n> / path-<date>, not e.g. access.log_old
+		if sep := name[len(base)]; sep != '.' && sep != '-' {
+			continue
+		}
+		if !IsRotatedOrCompressed(name) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		files = append(files, rotated{filepath.Join(dir, name), info.ModTime()})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		if !files[i].modTime.Equal(files[j].modTime) {
+			return files[i].modTime.Before(files[j].modTime)
+		}
+		return files[i].path > files[j].path // path.2 before path.1
+	})
+
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.path
+	}
+	return paths
+}
+
+// backfill feeds the rotated copies of path into handle in chronological
+// order. Returns false if it was interrupted by quit.
+func backfill(path string, handle func(line string), quit <-chan struct{}) bool {
+	for _, old := range rotatedSiblings(path) {
+		log.Printf("Backfilling %s", old)
+		err := readArchive(old, handle, quit)
+		if err == errStopped {
+			return false
+		}
+		if err != nil {
+			log.Printf("Error backfilling %s: %v", old, err)
+		}
+	}
+	return true
+}
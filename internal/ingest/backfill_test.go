@@ -0,0 +1,59 @@
+package ingest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestBackfillOrder(t *testing.T) {
+	dir := t.TempDir()
+	live := filepath.Join(dir, "access.log")
+
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	gw.Write([]byte("second\n"))
+	gw.Close()
+
+	var zst bytes.Buffer
+	zw, _ := zstd.NewWriter(&zst)
+	zw.Write([]byte("first\n"))
+	zw.Close()
+
+	// Oldest file first; modification times decide the order
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{"access.log.3.zst", zst.Bytes()},
+		{"access.log.2.gz", gz.Bytes()},
+		{"access.log.1", []byte("third\n")},
+		{"access.log", []byte("live\n")},
+		{"access.log_old", []byte("unrelated\n")},
+	}
+	base := time.Now().Add(-time.Hour)
+	for i, f := range files {
+		p := filepath.Join(dir, f.name)
+		if err := os.WriteFile(p, f.data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		mtime := base.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(p, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	if !backfill(live, func(line string) { got = append(got, line) }, nil) {
+		t.Fatal("backfill() = false; want true")
+	}
+	if want := "first,second,third"; strings.Join(got, ",") != want {
+		t.Errorf("backfill() read %q; want %q", strings.Join(got, ","), want)
+	}
+}
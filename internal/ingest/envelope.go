@@ -0,0 +1,116 @@
+package ingest
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Envelope is a container runtime log format (see Input.Envelope). The
+// config names for them are defined by the config package.
+type Envelope int
+
+const (
+	NoEnvelope     Envelope = iota
+	EnvelopeCRI             // containerd/CRI-O
+	EnvelopeDocker          // Docker json-file
+	EnvelopeAuto            // Either, recognized per line
+)
+
+// maxEnvelopeLine bounds a line reassembled from partial records
+const maxEnvelopeLine = 1 << 20
+
+// unwrapper strips container runtime envelopes and joins partial records
+// (CRI "P" tag, Docker json-file chunks without a trailing newline) back
+// into the original line. Lines that are not wrapped are passed as is.
+type unwrapper struct {
+	format  Envelope
+	handle  Handler
+	partial map[string]*partialLine // Per stream, stdout and stderr interleave
+}
+
+// partialLine is a line being reassembled, done once all its records are
+type partialLine struct {
+	text  strings.Builder
+	dones []func()
+}
+
+func newUnwrapper(format Envelope, handle Handler) *unwrapper {
+	return &unwrapper{format: format, handle: handle, partial: make(map[string]*partialLine)}
+}
+
+// dockerRecord is one line of a Docker json-file log
+type dockerRecord struct {
+	Log    *string `json:"log"`
+	Stream string  `json:"stream"`
+	Time   string  `json:"time"`
+}
+
+// Add processes one line
+func (u *unwrapper) Add(line string, done func()) {
+	if u.format != EnvelopeCRI && strings.HasPrefix(line, "{") {
+		var rec dockerRecord
+		if json.Unmarshal([]byte(line), &rec) == nil && rec.Log != nil {
+			text, complete := strings.CutSuffix(*rec.Log, "\n")
+			u.add(rec.Stream, strings.TrimSuffix(text, "\r"), complete, done)
+			return
+		}
+	}
+	if u.format != EnvelopeDocker {
+		if stream, tag, text, ok := splitCRI(line); ok {
+			u.add(stream, text, !strings.HasPrefix(tag, "P"), done)
+			return
+		}
+	}
+	u.handle(line, done)
+}
+
+// add appends a record's text to its stream and hands the line on once
+// complete
+func (u *unwrapper) add(stream, text string, complete bool, done func()) {
+	p := u.partial[stream]
+	if p == nil && complete {
+		u.handle(text, done)
+		return
+	}
+	if p == nil {
+		p = &partialLine{}
+		u.partial[stream] = p
+	}
+	if p.text.Len()+len(text) <= maxEnvelopeLine {
+		p.text.WriteString(text)
+	}
+	p.dones = append(p.dones, done)
+	if complete {
+		delete(u.partial, stream)
+		u.handle(p.text.String(), doneAll(p.dones))
+	}
+}
+
+// Stop hands on the lines still being reassembled, as the aggregator does
+// with its pending event
+func (u *unwrapper) Stop() {
+	for stream, p := range u.partial {
+		delete(u.partial, stream)
+		u.handle(p.text.String(), doneAll(p.dones))
+	}
+}
+
+// splitCRI splits a CRI log line: "<RFC3339 time> <stdout|stderr> <tag> <text>",
+// where tag is "F" (full) or "P" (partial), possibly followed by ":"-separated flags
+func splitCRI(line string) (stream, tag, text string, ok bool) {
+	parts := strings.SplitN(line, " ", 4)
+	if len(parts) < 3 {
+		return "", "", "", false
+	}
+	if _, err := time.Parse(time.RFC3339Nano, parts[0]); err != nil {
+		return "", "", "", false
+	}
+	if parts[1] != "stdout" && parts[1] != "stderr" {
+		return "", "", "", false
+	}
+	if len(parts) == 4 {
+		text = parts[3]
+	}
+	return parts[1], parts[2], text, true
+}
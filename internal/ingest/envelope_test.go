@@ -0,0 +1,79 @@
+package ingest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnwrapper(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Envelope
+		lines  []string
+		want   []string
+	}{
+		{
+			"CRI with partial lines",
+			EnvelopeCRI,
+			[]string{
+				`2024-01-01T00:00:00.000000001Z stdout P 10.0.0.1 - - [01/Jan/2024:00:00:00 +0000] "GET /a`,
+				`2024-01-01T00:00:00.000000002Z stderr F W0101 warning`,
+				`2024-01-01T00:00:00.000000003Z stdout F  HTTP/1.1" 200 5 "-" "curl/8.0"`,
+				`2024-01-01T00:00:01Z stdout F`,
+			},
+			[]string{
+				`W0101 warning`,
+				`10.0.0.1 - - [01/Jan/2024:00:00:00 +0000] "GET /a HTTP/1.1" 200 5 "-" "curl/8.0"`,
+				``,
+			},
+		},
+		{
+			"Partial line handed on at stop",
+			EnvelopeCRI,
+			[]string{`2024-01-01T00:00:00Z stdout P half a`, `2024-01-01T00:00:01Z stdout P  line`},
+			[]string{`half a line`},
+		},
+		{
+			"Docker json-file",
+			EnvelopeDocker,
+			[]string{
+				`{"log":"method=GET path=/a status=200\n","stream":"stdout","time":"2024-01-01T00:00:00.1Z"}`,
+				`{"log":"method=POST path=/b","stream":"stdout","time":"2024-01-01T00:00:00.2Z"}`,
+				`{"log":" status=201\r\n","stream":"stdout","time":"2024-01-01T00:00:00.3Z"}`,
+			},
+			[]string{`method=GET path=/a status=200`, `method=POST path=/b status=201`},
+		},
+		{
+			"Auto passes other lines through",
+			EnvelopeAuto,
+			[]string{
+				`{"log":"wrapped\n","stream":"stderr","time":"2024-01-01T00:00:00Z"}`,
+				`2024-01-01T00:00:00Z stdout F also wrapped`,
+				`{"level":"info","msg":"plain json"}`,
+				`plain text`,
+			},
+			[]string{`wrapped`, `also wrapped`, `{"level":"info","msg":"plain json"}`, `plain text`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			done := 0
+			u := newUnwrapper(tt.format, func(line string, lineDone func()) {
+				got = append(got, line)
+				lineDone()
+			})
+			for _, line := range tt.lines {
+				u.Add(line, func() { done++ })
+			}
+			u.Stop()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lines = %q; want %q", got, tt.want)
+			}
+			if done != len(tt.lines) {
+				t.Errorf("%d records done; want %d", done, len(tt.lines))
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package ingest
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// rotatedSuffix matches names logrotate/newsyslog leave behind
+// (access.log.1, access.log-20240101, access.log.2.gz, ...) and the ones
+// kubelet rotates pod logs to (0.log.20240101-120000)
+var rotatedSuffix = regexp.MustCompile(`(\.\d+|-\d{8,10}|\.\d{8}-\d{6})(\.(gz|zst|bz2|xz))?$`)
+
+// IsRotatedOrCompressed reports whether name looks like a rotated or
+// compressed log rather than a live one. Such files are never tailed;
+// they are only read by backfill.
+func IsRotatedOrCompressed(name string) bool {
+	if rotatedSuffix.MatchString(name) {
+		return true
+	}
+	switch filepath.Ext(name) {
+	case ".gz", ".zst", ".bz2", ".xz", ".zip":
+		return true
+	}
+	return false
+}
+
+func hasGlobMeta(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// globFiles returns the live log files matching pattern
+func globFiles(pattern string) []string {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for _, m := range matches {
+		if isLiveLog(m) {
+			files = append(files, m)
+		}
+	}
+	return files
+}
+
+// listDir returns the live log files directly inside dir
+func listDir(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for _, e := range entries {
+		p := filepath.Join(dir, e.Name())
+		if isLiveLog(p) {
+			files = append(files, p)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+func isLiveLog(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	return !IsRotatedOrCompressed(filepath.Base(path))
+}
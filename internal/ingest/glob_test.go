@@ -0,0 +1,154 @@
+package ingest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestIsRotatedOrCompressed(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"access.log", false},
+		{"0.log", false},
+		{"access_log", false},
+		{"access.log.1", true},
+		{"access.log.2.gz", true},
+		{"access.log-20240101", true},
+		{"access.log-2024010112.zst", true},
+		{"0.log.20240101-120000", true},
+		{"0.log.20240101-120000.gz", true},
+		{"archive.zip", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRotatedOrCompressed(tt.name); got != tt.want {
+				t.Errorf("IsRotatedOrCompressed(%q) = %v; want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+// logDir creates the given files (and a subdirectory) in a temp dir
+func logDir(t *testing.T, names ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub.log"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestListDir(t *testing.T) {
+	dir := logDir(t, "b.log", "a.log", "a.log.1", "a.log-20240101.gz", "0.log.20240101-120000")
+	want := []string{filepath.Join(dir, "a.log"), filepath.Join(dir, "b.log")}
+	if got := listDir(dir); !reflect.DeepEqual(got, want) {
+		t.Errorf("listDir() = %q; want %q", got, want)
+	}
+	if got := listDir(filepath.Join(dir, "missing")); got != nil {
+		t.Errorf("listDir(missing) = %q; want nil", got)
+	}
+}
+
+func TestGlobFiles(t *testing.T) {
+	dir := logDir(t, "shop-access.log", "blog-access.log", "shop-access.log.1", "shop-error.log")
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"*-access.log*", []string{"blog-access.log", "shop-access.log"}},
+		{"*.log", []string{"blog-access.log", "shop-access.log", "shop-error.log"}},
+		{"none-*.log", nil},
+		{"[", nil}, // Malformed pattern
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			var want []string
+			for _, name := range tt.want {
+				want = append(want, filepath.Join(dir, name))
+			}
+			if got := globFiles(filepath.Join(dir, tt.pattern)); !reflect.DeepEqual(got, want) {
+				t.Errorf("globFiles(%q) = %q; want %q", tt.pattern, got, want)
+			}
+		})
+	}
+}
+
+func TestExpand(t *testing.T) {
+	dir := logDir(t, "shop.access.log", "blog.access.log", "shop.access.log.1")
+	vhost := regexp.MustCompile(`/(?P<vhost>[^/.]+)\.access\.log$`)
+
+	tests := []struct {
+		name string
+		in   Input
+		want []File
+	}{
+		{
+			"Missing file is kept",
+			Input{Service: "web", Path: filepath.Join(dir, "new.log")},
+			[]File{{Service: "web", Path: filepath.Join(dir, "new.log")}},
+		},
+		{
+			"Directory",
+			Input{Service: "web", Path: dir},
+			[]File{
+				{Service: "web", Path: filepath.Join(dir, "blog.access.log")},
+				{Service: "web", Path: filepath.Join(dir, "shop.access.log")},
+			},
+		},
+		{
+			"Glob with vhost",
+			Input{Service: "{vhost}-web", Path: filepath.Join(dir, "*.access.log"), VhostPattern: vhost},
+			[]File{
+				{Service: "blog-web", Path: filepath.Join(dir, "blog.access.log"), Vhost: "blog"},
+				{Service: "shop-web", Path: filepath.Join(dir, "shop.access.log"), Vhost: "shop"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := expand(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expand() = %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractVhost(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		path    string
+		want    string
+	}{
+		{"Named group", `(?P<site>[^/]+)/(?P<vhost>[^/]+)\.log$`, "/var/log/main/shop.log", "shop"},
+		{"First group", `/var/log/([^/]+)/access\.log$`, "/var/log/shop/access.log", "shop"},
+		{"No group", `access\.log$`, "/var/log/shop/access.log", ""},
+		{"No match", `/srv/([^/]+)/`, "/var/log/shop/access.log", ""},
+		{"No pattern", "", "/var/log/shop/access.log", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var re *regexp.Regexp
+			if tt.pattern != "" {
+				re = regexp.MustCompile(tt.pattern)
+			}
+			if got := extractVhost(re, tt.path); got != tt.want {
+				t.Errorf("extractVhost() = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
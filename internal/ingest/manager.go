@@ -0,0 +1,374 @@
+package ingest
+
+import (
+	"errors"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"log-sentry/internal/checkpoint"
+	"log-sentry/internal/tailer"
+)
+
+// Input declares what to follow and where its lines go. Path may be a single
+// file, a directory (every log file inside it) or a glob pattern such as
+// /var/log/nginx/*access*.log; patterns are re-expanded periodically so new
+// files are picked up and deleted ones are dropped.
+type Input struct {
+	// Key identifies the input across reloads. Inputs whose key is unchanged
+	// keep running untouched; changing any part of an input should change its key.
+	Key     string
+	Service string // May contain {vhost}, replaced per file
+	Path    string
+
+	// VhostPattern optionally extracts a vhost name from each matched file
+	// path: the "vhost" named group if present, otherwise the first group.
+	VhostPattern *regexp.Regexp
+
+	// NewHandler returns the line handler for one matched file. A line's
+	// offset is checkpointed once the handler returns.
+	NewHandler func(f File) func(line string)
+
+	// NewAsyncHandler is used instead of NewHandler by inputs that hand lines
+	// off to be processed elsewhere (the worker pool) or hold on to them. A
+	// line's offset is checkpointed once it and every line before it are
+	// done, so after a crash the lines still queued are read again rather
+	// than lost. stop, if not nil, is called once the file is no longer
+	// followed, so the handler can finish the lines it holds.
+	NewAsyncHandler func(f File) (handle Handler, stop func())
+
+	// Multiline, if set, joins continuation lines before they are handled
+	Multiline *Multiline
+
+	// Envelope, if set, unwraps container runtime log lines first
+	Envelope Envelope
+
+	// Header, if set, reports the lines that set up how the lines after
+	// them are read (W3C "#Fields:" directives). A file resumed past some
+	// of them has them handled again first.
+	Header func(line string) bool
+}
+
+// Handler processes one line and calls done once it has been processed.
+// done must be called exactly once per line, even for lines that are skipped.
+type Handler func(line string, done func())
+
+// handler returns the handler for f and its stop func (may be nil)
+func (in Input) handler(f File) (Handler, func()) {
+	if in.NewAsyncHandler != nil {
+		return in.NewAsyncHandler(f)
+	}
+	handle := in.NewHandler(f)
+	return func(line string, done func()) {
+		handle(line)
+		done()
+	}, nil
+}
+
+// File is a concrete file matched by an Input
+type File struct {
+	Service string
+	Path    string
+	Vhost   string
+}
+
+// stopTimeout bounds how long a stopped file waits for its lines to be
+// processed
+const stopTimeout = 5 * time.Second
+
+type running struct {
+	file File
+	quit chan struct{} // Closed to stop backfilling/tailing
+	done chan struct{} // Closed once the file is no longer processed
+}
+
+// Manager owns the running tailers and reconciles them against the
+// desired set of inputs, so inputs can be added or removed at runtime.
+// Read offsets are recorded per file, so an input restarted by a reload
+// resumes where it stopped; with a checkpoint store they also survive a
+// restart of the process.
+type Manager struct {
+	mu       sync.Mutex
+	inputs   []Input
+	running  map[string]*running // input key + "|" + file path
+	store    *checkpoint.Store
+	backfill bool
+}
+
+// NewManager creates a manager. store may be nil to disable checkpointing,
+// offsets are then only kept in memory. With backfill set, the rotated (and
+// possibly compressed) copies of each file seen for the first time are
+// processed, oldest first, before the live file; backfill requires a store
+// to remember which files were read.
+func NewManager(store *checkpoint.Store, backfill bool) *Manager {
+	m := &Manager{
+		running:  make(map[string]*running),
+		store:    store,
+		backfill: backfill && store != nil,
+	}
+	if store == nil {
+		m.store = checkpoint.NewMemory()
+	}
+	return m
+}
+
+// Apply sets the desired inputs, starting files that are not yet followed
+// and stopping the ones that are no longer wanted. Files present in both
+// sets are left alone.
+func (m *Manager) Apply(inputs []Input) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inputs = inputs
+	m.reconcile()
+}
+
+// Watch re-expands directory and glob inputs every interval
+func (m *Manager) Watch(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			m.mu.Lock()
+			m.reconcile()
+			m.mu.Unlock()
+		}
+	}()
+}
+
+// Stop stops every running input, once the lines already read are processed.
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range m.running {
+		close(r.quit) // All at once, they drain in parallel
+	}
+	for key, r := range m.running {
+		<-r.done
+		delete(m.running, key)
+	}
+	m.inputs = nil
+}
+
+// Count returns the number of files being followed.
+func (m *Manager) Count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.running)
+}
+
+func (m *Manager) reconcile() {
+	wanted := make(map[string]File)
+	owners := make(map[string]Input)
+	for _, in := range m.inputs {
+		if in.Path == "" {
+			continue
+		}
+		for _, f := range expand(in) {
+			key := in.Key + "|" + f.Path
+			wanted[key] = f
+			owners[key] = in
+		}
+	}
+
+	for key, r := range m.running {
+		if _, ok := wanted[key]; ok {
+			continue
+		}
+		log.Printf("Stopping %s input at: %s", r.file.Service, r.file.Path)
+		m.stop(r)
+		delete(m.running, key)
+
+		// Forget offsets of files that are gone for good
+		if _, err := os.Stat(r.file.Path); errors.Is(err, os.ErrNotExist) {
+			m.store.Delete(r.file.Path)
+		}
+	}
+
+	for key, f := range wanted {
+		if _, ok := m.running[key]; ok {
+			continue
+		}
+		m.running[key] = m.start(f, owners[key])
+	}
+}
+
+// expand resolves an input into the files it currently covers. A plain
+// file path is always returned, even if it does not exist yet, so the
+// tailer can wait for it to appear.
+func expand(in Input) []File {
+	var paths []string
+	if info, err := os.Stat(in.Path); err == nil && info.IsDir() {
+		paths = listDir(in.Path)
+	} else if hasGlobMeta(in.Path) {
+		paths = globFiles(in.Path)
+	} else {
+		paths = []string{in.Path}
+	}
+
+	files := make([]File, 0, len(paths))
+	for _, p := range paths {
+		vhost := extractVhost(in.VhostPattern, p)
+		files = append(files, File{
+			Service: strings.ReplaceAll(in.Service, "{vhost}", vhost),
+			Path:    p,
+			Vhost:   vhost,
+		})
+	}
+	return files
+}
+
+func extractVhost(re *regexp.Regexp, path string) string {
+	if re == nil {
+		return ""
+	}
+	m := re.FindStringSubmatch(path)
+	if m == nil {
+		return ""
+	}
+	if i := re.SubexpIndex("vhost"); i > 0 {
+		return m[i]
+	}
+	if len(m) > 1 {
+		return m[1]
+	}
+	return ""
+}
+
+func (m *Manager) start(f File, in Input) *running {
+	log.Printf("Monitoring %s logs at: %s", f.Service, f.Path)
+	r := &running{file: f, quit: make(chan struct{}), done: make(chan struct{})}
+	go m.follow(r, in)
+	return r
+}
+
+// follow backfills (if enabled), then tails the file until r is stopped.
+// r.done is closed once the lines read are processed, so a restarted input
+// resumes past them.
+func (m *Manager) follow(r *running, in Input) {
+	defer close(r.done)
+	f := r.file
+
+	offsets := newOffsets(m.store)
+	defer func() {
+		if !offsets.wait(stopTimeout) {
+			log.Printf("Timed out waiting for the lines of %s to be processed", f.Path)
+		}
+	}()
+
+	handle, stop := in.handler(f)
+	if stop != nil {
+		defer stop() // Once the aggregator and unwrapper are flushed into it
+	}
+	if in.Multiline != nil {
+		agg := newAggregator(*in.Multiline, handle)
+		defer agg.Stop() // Pending event, before waiting for it
+		handle = agg.Add
+	}
+	if in.Envelope != NoEnvelope {
+		u := newUnwrapper(in.Envelope, handle)
+		defer u.Stop()
+		handle = u.Add
+	}
+
+	// Backfilled and rotated files are not checkpointed line by line
+	read := func(line string) { handle(line, func() {}) }
+	if m.backfill && !m.backfillFile(f.Path, read, r.quit) {
+		return
+	}
+	from := m.resume(f.Path, in.Header, read)
+
+	lines := make(chan tailer.Line)
+	t := tailer.TailFile(f.Path, from, lines)
+	if t == nil {
+		return
+	}
+	go func() {
+		<-r.quit
+		t.Stop()
+	}()
+
+	var id checkpoint.FileID
+	identified := false
+	for line := range lines {
+		// The tailer reopens the path after rotation/truncation, at which
+		// point the offset restarts; re-read the identity of the new file.
+		if !identified || line.Reopened {
+			id, _ = checkpoint.Identify(f.Path)
+			identified = true
+		}
+		handle(line.Text, offsets.add(checkpoint.Position{
+			Path:   f.Path,
+			Dev:    id.Dev,
+			Inode:  id.Inode,
+			Offset: line.Offset,
+		}))
+	}
+}
+
+// backfillFile reads the rotated copies of a file that has never been
+// checkpointed, before it is tailed from the start. The live file's identity
+// is checkpointed first, so a restart mid-backfill does not read anything
+// twice, and a rotation during backfill is caught up by resume.
+// Returns false if stopped in the meantime.
+func (m *Manager) backfillFile(path string, handle func(line string), quit <-chan struct{}) bool {
+	if _, ok := m.store.Get(path); ok {
+		return true // Already backfilled or tailed by a previous run
+	}
+	if id, err := checkpoint.Identify(path); err == nil {
+		m.store.Set(checkpoint.Position{Path: path, Dev: id.Dev, Inode: id.Inode})
+	}
+	return backfill(path, handle, quit)
+}
+
+// resume returns the offset to start tailing path from. If the file was
+// rotated since the checkpoint was taken, the rest of the rotated file is
+// processed first so nothing written during downtime is skipped. The header
+// lines before the offset resumed from are handled again.
+func (m *Manager) resume(path string, header func(line string) bool, handle func(line string)) int64 {
+	pos, ok := m.store.Get(path)
+	if !ok {
+		return 0
+	}
+	id, err := checkpoint.Identify(path)
+	if err != nil {
+		return 0 // Not there yet, the tailer waits for it
+	}
+
+	if pos.Matches(id) {
+		if pos.Offset <= id.Size {
+			log.Printf("Resuming %s at offset %d", path, pos.Offset)
+			replayHeader(path, pos.Offset, header, handle)
+			return pos.Offset
+		}
+		log.Printf("%s was truncated, reading from the start", path)
+		return 0
+	}
+
+	if old := checkpoint.FindRotated(path, pos); old != "" {
+		log.Printf("%s was rotated, finishing %s from offset %d", path, old, pos.Offset)
+		replayHeader(old, pos.Offset, header, handle)
+		if err := ReadFrom(old, pos.Offset, handle); err != nil {
+			log.Printf("Error reading rotated file %s: %v", old, err)
+		}
+	}
+	return 0
+}
+
+// replayHeader handles the header lines of path before offset
+func replayHeader(path string, offset int64, header func(line string) bool, handle func(line string)) {
+	if header == nil || offset == 0 {
+		return
+	}
+	if err := readHeader(path, offset, header, handle); err != nil {
+		log.Printf("Error reading the header of %s: %v", path, err)
+	}
+}
+
+func (m *Manager) stop(r *running) {
+	close(r.quit)
+	<-r.done
+}
@@ -0,0 +1,209 @@
+package ingest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"log-sentry/internal/checkpoint"
+)
+
+// collect returns an input handing the lines of path to a channel
+func collect(key, path string) (Input, chan string) {
+	lines := make(chan string, 100)
+	return Input{
+		Key:     key,
+		Service: "test",
+		Path:    path,
+		NewHandler: func(f File) func(string) {
+			return func(line string) { lines <- line }
+		},
+	}, lines
+}
+
+// expectLines waits for want on lines, then checks nothing else arrives
+func expectLines(t *testing.T, lines chan string, want ...string) {
+	t.Helper()
+	var got []string
+	timeout := time.After(5 * time.Second)
+	for len(got) < len(want) {
+		select {
+		case line := <-lines:
+			got = append(got, line)
+		case <-timeout:
+			t.Fatalf("got lines %q; want %q", got, want)
+		}
+	}
+	select {
+	case line := <-lines:
+		got = append(got, line)
+	case <-time.After(300 * time.Millisecond):
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got lines %q; want %q", got, want)
+	}
+}
+
+func appendFile(t *testing.T, path, data string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestManagerRestartedInputResumes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	appendFile(t, path, "one\ntwo\n")
+
+	m := NewManager(nil, false) // No checkpoint store
+	defer m.Stop()
+
+	in, lines := collect("v1", path)
+	m.Apply([]Input{in})
+	expectLines(t, lines, "one", "two")
+
+	// A reload changing the input's key restarts it where it stopped
+	in, lines = collect("v2", path)
+	m.Apply([]Input{in})
+	appendFile(t, path, "three\n")
+	expectLines(t, lines, "three")
+}
+
+func TestManagerResume(t *testing.T) {
+	tests := []struct {
+		name   string
+		header func(line string) bool
+		setup  func(t *testing.T, path string) checkpoint.Position // Returns the checkpoint
+		want   []string
+	}{
+		{
+			"Same file resumes at offset",
+			nil,
+			func(t *testing.T, path string) checkpoint.Position {
+				appendFile(t, path, "one\ntwo\n")
+				return position(t, path, 4)
+			},
+			[]string{"two"},
+		},
+		{
+			"Truncated file restarts at 0",
+			nil,
+			func(t *testing.T, path string) checkpoint.Position {
+				appendFile(t, path, "one\n")
+				return position(t, path, 100)
+			},
+			[]string{"one"},
+		},
+		{
+			"Rotated file is finished first",
+			nil,
+			func(t *testing.T, path string) checkpoint.Position {
+				appendFile(t, path, "one\ntwo\n")
+				pos := position(t, path, 4)
+				if err := os.Rename(path, path+".1"); err != nil {
+					t.Fatal(err)
+				}
+				appendFile(t, path, "three\n")
+				return pos
+			},
+			[]string{"two", "three"},
+		},
+		{
+			"Header lines before offset handled again",
+			isDirective,
+			func(t *testing.T, path string) checkpoint.Position {
+				appendFile(t, path, "#Fields: a\none\n#Fields: b\ntwo\nthree\n")
+				return position(t, path, 30)
+			},
+			[]string{"#Fields: a", "#Fields: b", "three"},
+		},
+		{
+			"Header of rotated file handled again",
+			isDirective,
+			func(t *testing.T, path string) checkpoint.Position {
+				appendFile(t, path, "#Fields: a\none\ntwo\n")
+				pos := position(t, path, 15)
+				if err := os.Rename(path, path+".1"); err != nil {
+					t.Fatal(err)
+				}
+				appendFile(t, path, "#Fields: b\nthree\n")
+				return pos
+			},
+			[]string{"#Fields: a", "two", "#Fields: b", "three"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "access.log")
+			store := checkpoint.NewMemory()
+			store.Set(tt.setup(t, path))
+
+			m := NewManager(store, false)
+			defer m.Stop()
+			in, lines := collect("v1", path)
+			in.Header = tt.header
+			m.Apply([]Input{in})
+			expectLines(t, lines, tt.want...)
+		})
+	}
+}
+
+func isDirective(line string) bool { return strings.HasPrefix(line, "#") }
+
+// position returns the checkpoint of path at offset
+func position(t *testing.T, path string, offset int64) checkpoint.Position {
+	t.Helper()
+	id, err := checkpoint.Identify(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return checkpoint.Position{Path: path, Dev: id.Dev, Inode: id.Inode, Offset: offset}
+}
+
+func TestManagerStopsHandler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	appendFile(t, path, "one\n")
+
+	// The handler holds on to its lines until it is stopped
+	stopped := make(chan struct{})
+	var held []func()
+	in := Input{
+		Key:  "v1",
+		Path: path,
+		NewAsyncHandler: func(f File) (Handler, func()) {
+			return func(line string, done func()) { held = append(held, done) }, func() {
+				for _, done := range held {
+					done()
+				}
+				close(stopped)
+			}
+		},
+	}
+
+	store := checkpoint.NewMemory()
+	m := NewManager(store, false)
+	m.Apply([]Input{in})
+	time.Sleep(300 * time.Millisecond)
+	if _, ok := store.Get(path); ok {
+		t.Error("held line checkpointed before it was done")
+	}
+
+	m.Stop()
+	select {
+	case <-stopped:
+	default:
+		t.Fatal("handler not stopped")
+	}
+	if pos, _ := store.Get(path); pos.Offset != 4 {
+		t.Errorf("checkpointed offset %d after stop; want 4", pos.Offset)
+	}
+}
@@ -0,0 +1,105 @@
+package ingest
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Multiline joins continuation lines (stack traces, wrapped messages) to
+// the line that started them, so handlers see one event per entry.
+// At least one of Start and Continue must be set.
+type Multiline struct {
+	Start    *regexp.Regexp // Lines matching start a new event; others continue it
+	Continue *regexp.Regexp // Lines matching continue the current event
+	MaxLines int            // Lines kept per event, the rest are dropped (0: no limit)
+	Timeout  time.Duration  // Emit a pending event after this long without new lines
+}
+
+// aggregator buffers the lines of the current event and hands complete
+// events, joined with "\n", to handle. The event is done once all of its
+// lines are.
+type aggregator struct {
+	cfg    Multiline
+	handle Handler
+
+	mu    sync.Mutex
+	lines []string
+	dones []func()
+	timer *time.Timer
+}
+
+func newAggregator(cfg Multiline, handle Handler) *aggregator {
+	return &aggregator{cfg: cfg, handle: handle}
+}
+
+// continues reports whether line belongs to the event before it
+func (a *aggregator) continues(line string) bool {
+	if a.cfg.Start != nil && a.cfg.Start.MatchString(line) {
+		return false
+	}
+	if a.cfg.Continue != nil {
+		return a.cfg.Continue.MatchString(line)
+	}
+	return a.cfg.Start != nil
+}
+
+// Add processes one line
+func (a *aggregator) Add(line string, done func()) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.lines) > 0 && a.continues(line) {
+		if a.cfg.MaxLines <= 0 || len(a.lines) < a.cfg.MaxLines {
+			a.lines = append(a.lines, line)
+		}
+	} else {
+		a.flush()
+		a.lines = append(a.lines, line)
+	}
+	a.dones = append(a.dones, done) // Dropped lines too
+
+	if a.cfg.Timeout > 0 {
+		if a.timer == nil {
+			a.timer = time.AfterFunc(a.cfg.Timeout, a.Flush)
+		} else {
+			a.timer.Reset(a.cfg.Timeout)
+		}
+	}
+}
+
+// Flush emits the pending event, if any
+func (a *aggregator) Flush() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.flush()
+}
+
+func (a *aggregator) flush() {
+	if len(a.lines) == 0 {
+		return
+	}
+	event, done := strings.Join(a.lines, "\n"), doneAll(a.dones)
+	a.lines, a.dones = a.lines[:0], nil
+	a.handle(event, done)
+}
+
+// Stop emits the pending event and cancels the flush timer
+func (a *aggregator) Stop() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.timer != nil {
+		a.timer.Stop()
+	}
+	a.flush()
+}
+
+// doneAll returns a func completing each of dones, for lines joined into one
+func doneAll(dones []func()) func() {
+	return func() {
+		for _, done := range dones {
+			done()
+		}
+	}
+}
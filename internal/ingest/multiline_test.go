@@ -0,0 +1,92 @@
+package ingest
+
+import (
+	"reflect"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAggregator(t *testing.T) {
+	trace := []string{
+		"2023-12-12 14:00:00 ERROR request failed",
+		"java.lang.IllegalStateException: boom",
+		"\tat com.example.A.a(A.java:1)",
+		"\tat com.example.B.b(B.java:2)",
+		"2023-12-12 14:00:01 INFO recovered",
+	}
+	tests := []struct {
+		name string
+		cfg  Multiline
+		want []string
+	}{
+		{
+			"Start pattern",
+			Multiline{Start: regexp.MustCompile(`^\d{4}-`)},
+			[]string{
+				"2023-12-12 14:00:00 ERROR request failed\njava.lang.IllegalStateException: boom\n\tat com.example.A.a(A.java:1)\n\tat com.example.B.b(B.java:2)",
+				"2023-12-12 14:00:01 INFO recovered",
+			},
+		},
+		{
+			"Continue pattern",
+			Multiline{Continue: regexp.MustCompile(`^\s+at `)},
+			[]string{
+				"2023-12-12 14:00:00 ERROR request failed",
+				"java.lang.IllegalStateException: boom\n\tat com.example.A.a(A.java:1)\n\tat com.example.B.b(B.java:2)",
+				"2023-12-12 14:00:01 INFO recovered",
+			},
+		},
+		{
+			"Max lines",
+			Multiline{Start: regexp.MustCompile(`^\d{4}-`), MaxLines: 2},
+			[]string{
+				"2023-12-12 14:00:00 ERROR request failed\njava.lang.IllegalStateException: boom",
+				"2023-12-12 14:00:01 INFO recovered",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			done := 0
+			a := newAggregator(tt.cfg, func(event string, eventDone func()) {
+				got = append(got, event)
+				eventDone()
+			})
+			for _, line := range trace {
+				a.Add(line, func() { done++ })
+			}
+			a.Stop()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events = %q; want %q", got, tt.want)
+			}
+			if done != len(trace) {
+				t.Errorf("%d lines done; want %d", done, len(trace))
+			}
+		})
+	}
+}
+
+func TestAggregatorTimeout(t *testing.T) {
+	var mu sync.Mutex
+	var got []string
+	a := newAggregator(Multiline{Start: regexp.MustCompile(`^\S`), Timeout: 20 * time.Millisecond}, func(event string, done func()) {
+		mu.Lock()
+		got = append(got, event)
+		mu.Unlock()
+	})
+	defer a.Stop()
+
+	a.Add("first", func() {})
+	a.Add("  continued", func() {})
+	time.Sleep(100 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"first\n  continued"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,71 @@
+package ingest
+
+import (
+	"sync"
+	"time"
+
+	"log-sentry/internal/checkpoint"
+)
+
+// offsets checkpoints the position of a file only once every line before it
+// has been processed. Lines handed to the worker pool complete out of order,
+// so the position recorded is that of the longest processed prefix.
+type offsets struct {
+	store *checkpoint.Store
+
+	mu      sync.Mutex
+	pending []*offset // In file order
+}
+
+type offset struct {
+	pos  checkpoint.Position
+	done bool
+}
+
+func newOffsets(store *checkpoint.Store) *offsets {
+	return &offsets{store: store}
+}
+
+// add registers the line ending at pos and returns the func to call once it
+// has been processed
+func (o *offsets) add(pos checkpoint.Position) func() {
+	p := &offset{pos: pos}
+	o.mu.Lock()
+	o.pending = append(o.pending, p)
+	o.mu.Unlock()
+	return func() { o.complete(p) }
+}
+
+func (o *offsets) complete(p *offset) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	p.done = true
+
+	n := 0
+	for n < len(o.pending) && o.pending[n].done {
+		n++
+	}
+	if n == 0 {
+		return
+	}
+	o.store.Set(o.pending[n-1].pos)
+	o.pending = o.pending[n:]
+}
+
+// wait waits until every line added has been processed, or timeout elapses.
+// Returns false on timeout.
+func (o *offsets) wait(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		o.mu.Lock()
+		n := len(o.pending)
+		o.mu.Unlock()
+		if n == 0 {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,37 @@
+package ingest
+
+import (
+	"testing"
+	"time"
+
+	"log-sentry/internal/checkpoint"
+)
+
+func TestOffsetsCommitProcessedPrefix(t *testing.T) {
+	store := checkpoint.NewMemory()
+	o := newOffsets(store)
+	var dones []func()
+	for _, offset := range []int64{10, 20, 30} {
+		dones = append(dones, o.add(checkpoint.Position{Path: "a.log", Offset: offset}))
+	}
+
+	// Completed out of order, as by the worker pool
+	steps := []struct {
+		done int
+		want int64 // Checkpointed offset, 0 if none
+	}{
+		{1, 0},
+		{0, 20},
+		{2, 30},
+	}
+	for _, step := range steps {
+		dones[step.done]()
+		pos, _ := store.Get("a.log")
+		if pos.Offset != step.want {
+			t.Errorf("after line %d: offset %d; want %d", step.done, pos.Offset, step.want)
+		}
+	}
+	if !o.wait(time.Second) {
+		t.Error("wait timed out with every line done")
+	}
+}
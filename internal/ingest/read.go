@@ -0,0 +1,101 @@
+package ingest
+
+import (
+	"bufio"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// maxLineSize bounds a single line when reading files directly
+const maxLineSize = 1024 * 1024
+
+// ReadFrom feeds every line of path from byte offset to EOF into handle
+func ReadFrom(path string, offset int64, handle func(line string)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if offset > 0 {
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+	}
+	return scanLines(f, handle, nil)
+}
+
+// readHeader feeds the lines of path before byte offset that isHeader
+// reports into handle
+func readHeader(path string, offset int64, isHeader func(line string) bool, handle func(line string)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return scanLines(io.LimitReader(f, offset), func(line string) {
+		if isHeader(line) {
+			handle(line)
+		}
+	}, nil)
+}
+
+// readArchive feeds every line of a plain or compressed (gzip, zstd,
+// bzip2) file into handle. It stops early with errStopped once quit is closed.
+func readArchive(path string, handle func(line string), quit <-chan struct{}) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader
+	switch filepath.Ext(path) {
+	case ".gz":
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	case ".zst":
+		zr, err := zstd.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = zr
+	case ".bz2":
+		r = bzip2.NewReader(f)
+	case ".xz", ".zip":
+		return fmt.Errorf("unsupported compression %s", filepath.Ext(path))
+	default:
+		r = f
+	}
+	return scanLines(r, handle, quit)
+}
+
+// errStopped is returned when reading is interrupted by a stop request
+var errStopped = errors.New("stopped")
+
+func scanLines(r io.Reader, handle func(line string), quit <-chan struct{}) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		select {
+		case <-quit:
+			return errStopped
+		default:
+		}
+		handle(scanner.Text())
+	}
+	return scanner.Err()
+}
@@ -2,12 +2,14 @@ package monitor
 
 import (
 	"os"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 )
 
 type FIM struct {
+	mu    sync.Mutex
 	Paths []string
 	FileHashes map[string]int64 // For V2.2 we monitor ModTime and Size for speed/simplicity
 	ChangeMetric *prometheus.CounterVec
@@ -29,6 +31,12 @@ func (f *FIM) Register(reg prometheus.Registerer) {
 }
 
 func (f *FIM) AddPath(path string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.addPath(path)
+}
+
+func (f *FIM) addPath(path string) {
 	f.Paths = append(f.Paths, path)
 	// Seed initial state
 	info, err := os.Stat(path)
@@ -37,6 +45,33 @@ func (f *FIM) AddPath(path string) {
 	}
 }
 
+// SetPaths replaces the watched paths. Paths kept across the change retain
+// their last known state so no spurious change is reported.
+func (f *FIM) SetPaths(paths []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	wanted := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		wanted[p] = true
+	}
+	for p := range f.FileHashes {
+		if !wanted[p] {
+			delete(f.FileHashes, p)
+		}
+	}
+
+	old := f.Paths
+	f.Paths = nil
+	for _, p := range paths {
+		if contains(old, p) {
+			f.Paths = append(f.Paths, p)
+			continue
+		}
+		f.addPath(p)
+	}
+}
+
 func (f *FIM) Start(interval time.Duration) {
 	go func() {
 		ticker := time.NewTicker(interval)
@@ -50,6 +85,9 @@ func (f *FIM) Start(interval time.Duration) {
 }
 
 func (f *FIM) checkAll() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
 	for _, path := range f.Paths {
 		info, err := os.Stat(path)
 		if err != nil {
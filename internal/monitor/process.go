@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -11,6 +12,7 @@ import (
 )
 
 type ProcessSentinel struct {
+	mu        sync.RWMutex
 	Blacklist []string
 	AlertMetric *prometheus.GaugeVec
 }
@@ -29,6 +31,13 @@ func (p *ProcessSentinel) Register(reg prometheus.Registerer) {
 	reg.MustRegister(p.AlertMetric)
 }
 
+// SetBlacklist replaces the list of forbidden process names
+func (p *ProcessSentinel) SetBlacklist(names []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.Blacklist = append([]string(nil), names...)
+}
+
 func (p *ProcessSentinel) Start(interval time.Duration) {
 	go func() {
 		ticker := time.NewTicker(interval)
@@ -72,6 +81,9 @@ func (p *ProcessSentinel) scan() {
 // isBlacklisted checks if the process name matches any blacklisted term exactly.
 // Returns the matched term and true if found.
 func (p *ProcessSentinel) isBlacklisted(procName string) (string, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
 	lowerName := strings.ToLower(procName)
 	for _, bad := range p.Blacklist {
 		// Exact match to avoid false positives (e.g., "nc" matching "runc")
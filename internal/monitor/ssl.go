@@ -4,12 +4,14 @@ import (
 	"crypto/tls"
 	"log"
 	"net"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 )
 
 type SSLMonitor struct {
+	mu      sync.Mutex
 	Targets []string // e.g., "localhost:443"
 	ExpiryMetric *prometheus.GaugeVec
 }
@@ -30,9 +32,31 @@ func (s *SSLMonitor) Register(reg prometheus.Registerer) {
 }
 
 func (s *SSLMonitor) AddTarget(target string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.Targets = append(s.Targets, target)
 }
 
+// SetTargets replaces the target list. Series of removed targets are
+// dropped and newly added targets are checked right away.
+func (s *SSLMonitor) SetTargets(targets []string) {
+	s.mu.Lock()
+	old := s.Targets
+	s.Targets = append([]string(nil), targets...)
+	s.mu.Unlock()
+
+	for _, t := range old {
+		if !contains(targets, t) {
+			s.ExpiryMetric.DeletePartialMatch(prometheus.Labels{"target": t})
+		}
+	}
+	for _, t := range targets {
+		if !contains(old, t) {
+			go s.checkOne(t)
+		}
+	}
+}
+
 func (s *SSLMonitor) Start(interval time.Duration) {
 	go func() {
 		ticker := time.NewTicker(interval)
@@ -46,7 +70,11 @@ func (s *SSLMonitor) Start(interval time.Duration) {
 }
 
 func (s *SSLMonitor) checkAll() {
-	for _, target := range s.Targets {
+	s.mu.Lock()
+	targets := append([]string(nil), s.Targets...)
+	s.mu.Unlock()
+
+	for _, target := range targets {
 		s.checkOne(target)
 	}
 }
@@ -65,3 +93,12 @@ func (s *SSLMonitor) checkOne(target string) {
 		s.ExpiryMetric.WithLabelValues(target, cert.Subject.CommonName).Set(days)
 	}
 }
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
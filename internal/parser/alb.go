@@ -0,0 +1,175 @@
+package parser
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ALBParser reads AWS load balancer access logs: Application Load Balancer
+// (first field is the request type) and Classic ELB (starts with the time)
+type ALBParser struct{}
+
+// Application Load Balancer access log fields (space separated, some quoted)
+// https 2018-07-02T22:23:00.186641Z app/my-lb/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.086 0.048 0.037 200 200 0 57
+// "GET https://www.example.com:443/ HTTP/1.1" "curl/7.46.0" ECDHE-RSA-AES128-GCM-SHA256 TLSv1.2 arn:...:targetgroup/my-targets/73e2d6bc24d8a067
+// "Root=1-58337281-1d84f3d73c47ec4e58577259" "www.example.com" "arn:...:certificate/..." 1 2018-07-02T22:22:48.364000Z "forward" "-" "-" "10.0.0.1:80" "200" "-" "-"
+const (
+	albType = iota
+	albTime
+	albName
+	albClient
+	albTarget
+	albRequestTime  // request_processing_time (seconds, -1 if not reached)
+	albTargetTime   // target_processing_time
+	albResponseTime // response_processing_time
+	albELBStatus
+	albTargetStatus
+	albReceived
+	albSent
+	albRequest
+	albUserAgent
+	albCipher
+	albTLSProtocol
+	albTargetGroup
+	albTraceID
+	albDomain
+	albCertificate
+	albRulePriority
+	albCreationTime
+	albActions
+	albRedirectURL
+	albErrorReason
+	albTargetList
+	albTargetStatusList
+	albClassification
+	albClassificationReason
+)
+
+// albExtras are the ALB values kept in Extra
+var albExtras = map[int]string{
+	albType:                 "lb_type",
+	albName:                 "load_balancer",
+	albTargetGroup:          "target_group",
+	albRulePriority:         "matched_rule_priority",
+	albActions:              "actions_executed",
+	albRedirectURL:          "redirect_url",
+	albErrorReason:          "error_reason",
+	albClassification:       "classification",
+	albClassificationReason: "classification_reason",
+}
+
+func (p *ALBParser) Parse(line string) (*GenericLogEntry, error) {
+	fields := splitQuoted(line)
+	if len(fields) >= 15 && len(fields[0]) > 0 && fields[0][0] >= '0' && fields[0][0] <= '9' {
+		// Classic ELB: same layout as ALB up to ssl_protocol, without the type
+		fields = append([]string{""}, fields...)
+	}
+	if len(fields) < albTLSProtocol+1 {
+		return nil, fmt.Errorf("failed to parse alb line: %s", line)
+	}
+	t, err := time.Parse(time.RFC3339Nano, fields[albTime])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse alb line: %s", line)
+	}
+	get := func(i int) string {
+		if i < len(fields) {
+			return dash(fields[i])
+		}
+		return ""
+	}
+
+	status, _ := strconv.Atoi(fields[albELBStatus])
+	received, _ := strconv.Atoi(fields[albReceived])
+	sent, _ := strconv.Atoi(fields[albSent])
+
+	entry := &GenericLogEntry{
+		Service:       "alb",
+		RemoteIP:      stripPort(fields[albClient]),
+		RemoteUser:    "-",
+		TimeLocal:     t,
+		Status:        status,
+		BodyBytesSent: sent,
+		UserAgent:     get(albUserAgent),
+		RequestBytes:  received,
+		RequestID:     get(albTraceID),
+		UpstreamAddr:  get(albTarget),
+		TLSCipher:     get(albCipher),
+		TLSProtocol:   get(albTLSProtocol),
+	}
+
+	// "GET https://www.example.com:443/path?q HTTP/1.1" (the full URL)
+	parts := strings.SplitN(fields[albRequest], " ", 3)
+	entry.Method = parts[0]
+	if len(parts) > 1 {
+		entry.Path = parts[1]
+		if u, err := url.Parse(parts[1]); err == nil && u.Host != "" {
+			entry.Path = u.RequestURI()
+			entry.Host = u.Hostname()
+		}
+	}
+	if len(parts) > 2 {
+		entry.Protocol = parts[2]
+	}
+	if domain := get(albDomain); domain != "" {
+		entry.Host = domain
+	}
+
+	// Processing times are -1 when the load balancer could not reach (or
+	// lost) the target; the latency is then unknown
+	var times []float64
+	for _, i := range []int{albRequestTime, albTargetTime, albResponseTime} {
+		if v, err := strconv.ParseFloat(fields[i], 64); err == nil && v >= 0 {
+			times = append(times, v)
+		}
+	}
+	if len(times) == 3 {
+		entry.Latency = times[0] + times[1] + times[2]
+		entry.UpstreamLatency = times[1]
+	}
+
+	if target := get(albTargetStatus); target != "" {
+		entry.SetExtra("upstream_status", target)
+	}
+	for i, key := range albExtras {
+		if v := get(i); v != "" {
+			entry.SetExtra(key, v)
+		}
+	}
+	return entry, nil
+}
+
+// splitQuoted splits a line on spaces, keeping double-quoted values (with
+// the quotes removed) as one field
+func splitQuoted(line string) []string {
+	var fields []string
+	for i := 0; i < len(line); {
+		switch {
+		case line[i] == ' ':
+			i++
+		case line[i] == '"':
+			end := i + 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end > len(line) {
+				end = len(line)
+			}
+			fields = append(fields, line[i+1:end])
+			i = end + 1
+		default:
+			end := strings.IndexByte(line[i:], ' ')
+			if end < 0 {
+				end = len(line) - i
+			}
+			fields = append(fields, line[i:i+end])
+			i += end
+		}
+	}
+	return fields
+}
@@ -0,0 +1,69 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestALBParser(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		want  GenericLogEntry
+		extra map[string]string
+		time  time.Time
+	}{
+		{
+			"Application Load Balancer",
+			`https 2018-07-02T22:23:00.186641Z app/my-lb/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.5 0.25 0.125 502 500 34 57 "GET https://www.example.com:443/a?b=1 HTTP/1.1" "curl/7.46.0" ECDHE-RSA-AES128-GCM-SHA256 TLSv1.2 arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 "Root=1-58337281-1d84f3d73c47ec4e58577259" "www.example.com" "arn:aws:acm:us-east-2:123456789012:certificate/12345678" 1 2018-07-02T22:22:48.364000Z "forward" "-" "-" "10.0.0.1:80" "500" "-" "-"`,
+			GenericLogEntry{RemoteIP: "192.168.131.39", RemoteUser: "-", Method: "GET", Path: "/a?b=1", Protocol: "HTTP/1.1", Status: 502, BodyBytesSent: 57, UserAgent: "curl/7.46.0",
+				Latency: 0.875, Host: "www.example.com", RequestID: "Root=1-58337281-1d84f3d73c47ec4e58577259", RequestBytes: 34, UpstreamAddr: "10.0.0.1:80", UpstreamLatency: 0.25,
+				TLSProtocol: "TLSv1.2", TLSCipher: "ECDHE-RSA-AES128-GCM-SHA256"},
+			map[string]string{
+				"lb_type": "https", "load_balancer": "app/my-lb/50dc6c495c0c9188", "target_group": "arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067",
+				"upstream_status": "500", "matched_rule_priority": "1", "actions_executed": "forward",
+			},
+			time.Date(2018, 7, 2, 22, 23, 0, 186641000, time.UTC),
+		},
+		{
+			"Target not reached",
+			`http 2018-07-02T22:23:00.186641Z app/my-lb/50dc6c495c0c9188 192.168.131.39:2817 - -1 -1 -1 503 - 34 366 "GET http://www.example.com:80/ HTTP/1.1" "curl/7.46.0" - - arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 "Root=1-58337364-23a8c76965a2ef7629b185e3" "-" "-" 0 2018-07-02T22:22:48.364000Z "forward" "-" "-" "-" "-" "-" "-"`,
+			GenericLogEntry{RemoteIP: "192.168.131.39", RemoteUser: "-", Method: "GET", Path: "/", Protocol: "HTTP/1.1", Status: 503, BodyBytesSent: 366, UserAgent: "curl/7.46.0",
+				Host: "www.example.com", RequestID: "Root=1-58337364-23a8c76965a2ef7629b185e3", RequestBytes: 34},
+			map[string]string{
+				"lb_type": "http", "load_balancer": "app/my-lb/50dc6c495c0c9188", "target_group": "arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067",
+				"matched_rule_priority": "0", "actions_executed": "forward",
+			},
+			time.Date(2018, 7, 2, 22, 23, 0, 186641000, time.UTC),
+		},
+		{
+			"Classic ELB",
+			`2015-05-13T23:39:43.945958Z my-loadbalancer 192.168.131.39:2817 10.0.0.1:80 0.5 0.25 0.125 200 200 0 29 "GET http://www.example.com:80/ HTTP/1.1" "curl/7.38.0" - -`,
+			GenericLogEntry{RemoteIP: "192.168.131.39", RemoteUser: "-", Method: "GET", Path: "/", Protocol: "HTTP/1.1", Status: 200, BodyBytesSent: 29, UserAgent: "curl/7.38.0",
+				Latency: 0.875, Host: "www.example.com", UpstreamAddr: "10.0.0.1:80", UpstreamLatency: 0.25},
+			map[string]string{"load_balancer": "my-loadbalancer", "upstream_status": "200"},
+			time.Date(2015, 5, 13, 23, 39, 43, 945958000, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := (&ALBParser{}).Parse(tt.line)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !got.TimeLocal.Equal(tt.time) {
+				t.Errorf("TimeLocal = %v; want %v", got.TimeLocal, tt.time)
+			}
+			extra := got.Extra
+			got.TimeLocal, got.Service, got.Extra = time.Time{}, "", nil
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Parse() = %+v; want %+v", *got, tt.want)
+			}
+			if !reflect.DeepEqual(map[string]string(extra), tt.extra) {
+				t.Errorf("Parse() extra = %v; want %v", extra, tt.extra)
+			}
+		})
+	}
+}
@@ -0,0 +1,391 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ApacheFormatParser parses lines written with a custom Apache LogFormat.
+// Each %directive becomes a capture group; well-known directives are mapped
+// onto GenericLogEntry (%V/%v as Host, %I as RequestBytes, mod_ssl's
+// %{SSL_PROTOCOL}x, ...) and the rest are kept in Extra, named like the
+// equivalent nginx variable where there is one (%{X-Api-Key}i is
+// "http_x_api_key", %O is "bytes_sent", ...). Without %b or %B, %O is
+// also used as BodyBytesSent.
+type ApacheFormatParser struct {
+	Format     string
+	re         *regexp.Regexp
+	directives []apacheDirective // Directive captured by each group, in order
+	sentFromO  bool              // BodyBytesSent from %O (no %b/%B)
+}
+
+type apacheDirective struct {
+	verb  string // Directive letter, with "<" or ">" for %<s / %>s
+	param string // Content of {...}, if any
+}
+
+// apacheDirectiveRegex matches %[<>][!]codes{param}X after the '%'
+var apacheDirectiveRegex = regexp.MustCompile(`^%([<>]?)(?:!?\d{3}(?:,\d{3})*)?(?:\{([^}]*)\})?([<>]?)([a-zA-Z%])`)
+
+// NewApacheFormatParser compiles an Apache LogFormat string, e.g.
+// `%h %l %u %t "%r" %>s %b "%{Referer}i" "%{User-Agent}i" %D`.
+// Backslash escapes as written in httpd.conf (\", \t) are understood.
+func NewApacheFormatParser(format string) (*ApacheFormatParser, error) {
+	format = unescapeApache(format)
+	p := &ApacheFormatParser{Format: format}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i := 0; i < len(format); {
+		if format[i] != '%' {
+			j := strings.IndexByte(format[i:], '%')
+			if j < 0 {
+				j = len(format) - i
+			}
+			expr.WriteString(regexp.QuoteMeta(format[i : i+j]))
+			i += j
+			continue
+		}
+
+		m := apacheDirectiveRegex.FindStringSubmatch(format[i:])
+		if m == nil {
+			return nil, fmt.Errorf("invalid directive at %q", format[i:])
+		}
+		i += len(m[0])
+		d := apacheDirective{verb: m[1] + m[3] + m[4], param: m[2]}
+		if d.verb == "%" {
+			expr.WriteString("%")
+			continue
+		}
+		expr.WriteString("(" + apacheDirectivePattern(d, format[i:]) + ")")
+		p.directives = append(p.directives, d)
+	}
+	expr.WriteString("$")
+
+	if len(p.directives) == 0 {
+		return nil, fmt.Errorf("LogFormat has no directives: %q", format)
+	}
+	p.sentFromO = true
+	for _, d := range p.directives {
+		if d.verb == "b" || d.verb == "B" {
+			p.sentFromO = false
+		}
+	}
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile LogFormat %q: %v", format, err)
+	}
+	p.re = re
+	return p, nil
+}
+
+func unescapeApache(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	return strings.NewReplacer(`\"`, `"`, `\\`, `\`, `\t`, "\t", `\n`, "\n").Replace(s)
+}
+
+// apacheDirectivePattern returns the regex for a directive given the format
+// text that follows it
+func apacheDirectivePattern(d apacheDirective, rest string) string {
+	switch strings.TrimLeft(d.verb, "<>") {
+	case "h", "a", "l", "u", "m", "H", "p", "P":
+		return `\S+`
+	case "t":
+		if d.param == "" {
+			return `\[[^\]]+\]`
+		}
+		return strftimePattern(d.param)
+	case "s", "b", "B", "D", "I", "O", "S", "T", "k":
+		return `[\d.]+|-`
+	case "U":
+		return `[^\s?]*` // Usually followed by %q
+	case "q":
+		return `(?:\?\S*)?`
+	}
+	if rest == "" {
+		return `.*`
+	}
+	switch c := rest[0]; c {
+	case '%':
+		return `.*?` // Two directives back to back
+	case ' ', '\t':
+		return `\S*`
+	case '"':
+		return `(?:[^"\\]|\\.)*` // Apache escapes quotes inside fields as \"
+	default:
+		return `[^` + regexp.QuoteMeta(string(c)) + `]*`
+	}
+}
+
+// strftimeDirectives are the regexes of the strftime conversions
+var strftimeDirectives = map[byte]string{
+	'a': `[A-Za-z]+`, 'A': `[A-Za-z]+`, 'b': `[A-Za-z]+`, 'B': `[A-Za-z]+`, 'h': `[A-Za-z]+`, 'p': `[A-Za-z]+`,
+	'd': `\d{2}`, 'm': `\d{2}`, 'y': `\d{2}`, 'C': `\d{2}`, 'H': `\d{2}`, 'I': `\d{2}`, 'M': `\d{2}`, 'S': `\d{2}`,
+	'U': `\d{2}`, 'V': `\d{2}`, 'W': `\d{2}`, 'g': `\d{2}`,
+	'e': ` ?\d{1,2}`, 'k': ` ?\d{1,2}`, 'l': ` ?\d{1,2}`, // Space padded
+	'Y': `\d{4}`, 'G': `\d{4}`, 'j': `\d{3}`, 'u': `\d`, 'w': `\d`, 's': `\d+`,
+	'z': `[+-]\d{4}`, 'Z': `\S+`,
+	'D': `\d{2}/\d{2}/\d{2}`, 'F': `\d{4}-\d{2}-\d{2}`,
+	'R': `\d{2}:\d{2}`, 'T': `\d{2}:\d{2}:\d{2}`, 'r': `\d{2}:\d{2}:\d{2} [A-Za-z]+`,
+	'n': `\n`, 't': `\t`, '%': `%`,
+}
+
+// strftimePattern returns the regex for the time of %{format}t, derived
+// from the strftime format (which may contain spaces)
+func strftimePattern(format string) string {
+	format = strings.TrimPrefix(strings.TrimPrefix(format, "begin:"), "end:")
+	switch format {
+	case "sec", "msec", "usec", "msec_frac", "usec_frac":
+		return `\d+`
+	}
+
+	var expr strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			expr.WriteString(regexp.QuoteMeta(format[i : i+1]))
+			continue
+		}
+		i++
+		if pattern, ok := strftimeDirectives[format[i]]; ok {
+			expr.WriteString(pattern)
+		} else {
+			expr.WriteString(`.+?`) // Locale dependent (%c, %x, %X)
+		}
+	}
+	return expr.String()
+}
+
+// Parse implements LogParser
+func (p *ApacheFormatParser) Parse(line string) (*GenericLogEntry, error) {
+	matches := p.re.FindStringSubmatch(line)
+	if matches == nil {
+		return nil, fmt.Errorf("failed to parse apache line: %s", line)
+	}
+
+	entry := &GenericLogEntry{Service: "apache"}
+	for i, d := range p.directives {
+		value := matches[i+1]
+		if strings.Contains(value, `\`) {
+			value = strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(value)
+		}
+		if name := setApacheDirective(entry, d, value); name != "" {
+			entry.SetExtra(name, value)
+		}
+		if d.verb == "O" && p.sentFromO {
+			entry.BodyBytesSent, _ = strconv.Atoi(value)
+		}
+	}
+	return entry, nil
+}
+
+// setApacheDirective maps a directive onto entry. Directives without a
+// dedicated field return the name to keep them under in Extra.
+func setApacheDirective(entry *GenericLogEntry, d apacheDirective, value string) string {
+	if value == "-" {
+		value = ""
+	}
+	switch d.verb {
+	case "a":
+		if d.param == "c" {
+			return "peer_addr"
+		}
+		entry.RemoteIP = value
+	case "h":
+		if d.param == "c" {
+			return "peer_host"
+		}
+		if entry.RemoteIP == "" { // %a is preferred when both are logged
+			entry.RemoteIP = value
+		}
+	case "u":
+		entry.RemoteUser = value
+	case "t":
+		return setApacheTime(entry, d.param, value)
+	case "r":
+		parts := strings.SplitN(value, " ", 3)
+		entry.Method = parts[0]
+		if len(parts) > 1 {
+			entry.Path = parts[1]
+		}
+		if len(parts) > 2 {
+			entry.Protocol = parts[2]
+		}
+	case "m":
+		entry.Method = value
+	case "U":
+		if entry.Path == "" || entry.Path[0] == '?' { // %q may come first
+			entry.Path = value + entry.Path
+		}
+	case "q":
+		entry.Path += value // Query string, "?..." or empty
+	case "H":
+		entry.Protocol = value
+	case ">s":
+		entry.Status, _ = strconv.Atoi(value)
+	case "s", "<s":
+		if entry.Status == 0 { // Final status (%>s) wins
+			entry.Status, _ = strconv.Atoi(value)
+		}
+	case "b", "B":
+		entry.BodyBytesSent, _ = strconv.Atoi(value)
+	case "D":
+		us, _ := strconv.ParseFloat(value, 64)
+		entry.Latency = us / 1e6
+	case "T":
+		if entry.Latency == 0 { // %D is more precise
+			entry.Latency = apacheDuration(d.param, value)
+		}
+	case "V":
+		entry.Host = value
+	case "v":
+		if entry.Host == "" {
+			entry.Host = value // Until %V says otherwise
+		}
+		return "server_name"
+	case "I":
+		entry.RequestBytes, _ = strconv.Atoi(value)
+	case "i":
+		switch strings.ToLower(d.param) {
+		case "referer":
+			entry.Referer = value
+		case "user-agent":
+			entry.UserAgent = value
+		case "x-forwarded-for":
+			entry.ForwardedFor = splitForwardedFor(value)
+		case "x-request-id":
+			entry.RequestID = value
+		default:
+			return "http_" + headerVar(d.param)
+		}
+	case "o":
+		return "sent_http_" + headerVar(d.param)
+	case "x":
+		switch d.param {
+		case "SSL_PROTOCOL":
+			entry.TLSProtocol = value
+		case "SSL_CIPHER":
+			entry.TLSCipher = value
+		default:
+			return strings.ToLower(d.param)
+		}
+	case "e":
+		switch d.param {
+		case "UNIQUE_ID":
+			if entry.RequestID == "" {
+				entry.RequestID = value
+			}
+		case "BALANCER_WORKER_NAME":
+			entry.UpstreamAddr = value
+		default:
+			return "env_" + d.param
+		}
+	case "C":
+		return "cookie_" + d.param
+	case "n":
+		return "note_" + d.param
+	default:
+		if name, ok := apacheExtraNames[d.verb]; ok {
+			return name
+		}
+		return "%" + d.verb
+	}
+	return ""
+}
+
+// apacheExtraNames names the directives kept in Extra
+var apacheExtraNames = map[string]string{
+	"l": "remote_logname",
+	"p": "server_port",
+	"P": "pid",
+	"O": "bytes_sent",
+	"S": "bytes_transferred",
+	"k": "keepalive_requests",
+	"X": "connection_status",
+	"L": "request_log_id",
+	"R": "handler",
+	"f": "request_filename",
+	"A": "server_addr",
+}
+
+// headerVar turns a header name into its nginx variable form (X-Real-IP -> x_real_ip)
+func headerVar(name string) string {
+	return strings.ReplaceAll(strings.ToLower(name), "-", "_")
+}
+
+// apacheDuration converts %T / %{unit}T into seconds
+func apacheDuration(unit, value string) float64 {
+	v, _ := strconv.ParseFloat(value, 64)
+	switch unit {
+	case "ms":
+		return v / 1e3
+	case "us":
+		return v / 1e6
+	default: // "s" or none
+		return v
+	}
+}
+
+// setApacheTime parses %t and the epoch forms of %{format}t. Other
+// strftime formats are kept in Extra as "time".
+func setApacheTime(entry *GenericLogEntry, format, value string) string {
+	format = strings.TrimPrefix(strings.TrimPrefix(format, "begin:"), "end:")
+	switch format {
+	case "":
+		value = strings.Trim(value, "[]")
+		entry.TimeLocal, _ = time.Parse("02/Jan/2006:15:04:05 -0700", value)
+	case "sec", "msec", "usec":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return "time"
+		}
+		switch format {
+		case "sec":
+			entry.TimeLocal = time.Unix(n, 0)
+		case "msec":
+			entry.TimeLocal = time.UnixMilli(n)
+		default:
+			entry.TimeLocal = time.UnixMicro(n)
+		}
+	default:
+		return "time"
+	}
+	return ""
+}
+
+// apacheNamedFormats are Apache's stock LogFormat nicknames
+var apacheNamedFormats = map[string]string{
+	"common":         `%h %l %u %t "%r" %>s %b`,
+	"combined":       `%h %l %u %t "%r" %>s %b "%{Referer}i" "%{User-Agent}i"`,
+	"vhost_combined": `%v:%p %h %l %u %t "%r" %>s %O "%{Referer}i" "%{User-Agent}i"`,
+}
+
+// logFormatLine matches `LogFormat "format" nickname` (with \" escapes inside)
+var logFormatLine = regexp.MustCompile(`(?m)^\s*LogFormat\s+"((?:[^"\\]|\\.)*)"\s+(\S+)`)
+
+// LoadApacheFormat reads the LogFormat with the given nickname from an
+// Apache config file, falling back to the stock common/combined formats.
+func LoadApacheFormat(confPath, name string) (string, error) {
+	data, err := os.ReadFile(confPath)
+	if err != nil {
+		if format, ok := apacheNamedFormats[name]; ok {
+			return format, nil
+		}
+		return "", err
+	}
+	conf := strings.ReplaceAll(string(data), "\\\n", " ") // Line continuations
+	for _, m := range logFormatLine.FindAllStringSubmatch(conf, -1) {
+		if m[2] == name {
+			return m[1], nil
+		}
+	}
+	if format, ok := apacheNamedFormats[name]; ok {
+		return format, nil
+	}
+	return "", fmt.Errorf("LogFormat %q not found in %s", name, confPath)
+}
@@ -0,0 +1,92 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestApacheFormatParser(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		line   string
+		want   GenericLogEntry
+		extra  map[string]string
+		time   time.Time
+	}{
+		{
+			"Combined with microseconds",
+			`%h %l %u %t \"%r\" %>s %b \"%{Referer}i\" \"%{User-Agent}i\" %D`,
+			`10.0.0.1 - - [12/Dec/2023:14:00:00 +0000] "GET /a?b=1 HTTP/1.1" 200 - "-" "Mozilla/5.0 (X11)" 1500`,
+			GenericLogEntry{RemoteIP: "10.0.0.1", Method: "GET", Path: "/a?b=1", Protocol: "HTTP/1.1", Status: 200, UserAgent: "Mozilla/5.0 (X11)", Latency: 0.0015},
+			map[string]string{"remote_logname": "-"},
+			time.Date(2023, 12, 12, 14, 0, 0, 0, time.UTC),
+		},
+		{
+			"Vhost, forwarded-for, logio bytes and TLS",
+			`%v %a "%m %U%q %H" %>s %I %O %{ms}T "%{X-Forwarded-For}i" %{SSL_PROTOCOL}x`,
+			`shop.example.com 10.0.0.2 "POST /cart?id=7 HTTP/2.0" 500 812 1024 250 "1.2.3.4, 10.0.0.9" TLSv1.3`,
+			GenericLogEntry{RemoteIP: "10.0.0.2", Method: "POST", Path: "/cart?id=7", Protocol: "HTTP/2.0", Status: 500, BodyBytesSent: 1024, Latency: 0.25,
+				Host: "shop.example.com", RequestBytes: 812, ForwardedFor: []string{"1.2.3.4", "10.0.0.9"}, TLSProtocol: "TLSv1.3"},
+			map[string]string{
+				"server_name": "shop.example.com",
+				"bytes_sent":  "1024",
+			},
+			time.Time{},
+		},
+		{
+			"vhost_combined counts %O as body bytes",
+			apacheNamedFormats["vhost_combined"],
+			`www.example.com:443 10.0.0.5 - - [12/Dec/2023:14:00:00 +0000] "GET / HTTP/1.1" 200 2326 "-" "curl/8.0"`,
+			GenericLogEntry{RemoteIP: "10.0.0.5", Method: "GET", Path: "/", Protocol: "HTTP/1.1", Status: 200, BodyBytesSent: 2326, UserAgent: "curl/8.0", Host: "www.example.com"},
+			map[string]string{"server_name": "www.example.com", "server_port": "443", "remote_logname": "-", "bytes_sent": "2326"},
+			time.Date(2023, 12, 12, 14, 0, 0, 0, time.UTC),
+		},
+		{
+			"strftime time with spaces",
+			`%h %{%d/%b/%Y %T}t "%r" %>s %b`,
+			`10.0.0.6 12/Dec/2023 14:00:00 "GET /t HTTP/1.1" 200 5`,
+			GenericLogEntry{RemoteIP: "10.0.0.6", Method: "GET", Path: "/t", Protocol: "HTTP/1.1", Status: 200, BodyBytesSent: 5},
+			map[string]string{"time": "12/Dec/2023 14:00:00"},
+			time.Time{},
+		},
+		{
+			"Escaped quotes inside fields",
+			`%h "%r" %s %T "%{User-Agent}i"`,
+			`10.0.0.3 "GET /x\"y HTTP/1.0" 404 2 "say \"hi\""`,
+			GenericLogEntry{RemoteIP: "10.0.0.3", Method: "GET", Path: `/x"y`, Protocol: "HTTP/1.0", Status: 404, UserAgent: `say "hi"`, Latency: 2},
+			nil,
+			time.Time{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewApacheFormatParser(tt.format)
+			if err != nil {
+				t.Fatalf("NewApacheFormatParser() error = %v", err)
+			}
+			got, err := p.Parse(tt.line)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !got.TimeLocal.Equal(tt.time) {
+				t.Errorf("TimeLocal = %v; want %v", got.TimeLocal, tt.time)
+			}
+			extra := got.Extra
+			got.TimeLocal, got.Service, got.Extra = time.Time{}, "", nil
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Parse() = %+v; want %+v", *got, tt.want)
+			}
+			if len(extra) != len(tt.extra) {
+				t.Errorf("Parse() extra = %v; want %v", extra, tt.extra)
+			}
+			for k, v := range tt.extra {
+				if extra[k] != v {
+					t.Errorf("Extra[%q] = %q; want %q", k, extra[k], v)
+				}
+			}
+		})
+	}
+}
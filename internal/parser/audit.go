@@ -0,0 +1,329 @@
+package parser
+
+import (
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AuditRecord is one line of the Linux audit log (audit.log)
+type AuditRecord struct {
+	Type   string // SYSCALL, EXECVE, CWD, PATH, PROCTITLE, EOE, USER_LOGIN, ...
+	ID     string // "1364481363.243:24287", shared by the records of an event
+	Time   time.Time
+	Fields map[string]string // Decoded; ENRICHED names (UID="alice") are upper case
+}
+
+// AuditEvent is an audit event reassembled from its records
+type AuditEvent struct {
+	ID   string
+	Time time.Time
+	Type string // Type of the first record, e.g. SYSCALL or USER_LOGIN
+
+	// SYSCALL record
+	Syscall string // Name with log_format=ENRICHED, number otherwise
+	Success bool
+	Exit    string
+	AUID    int    // Login user, -1 if unset (daemons)
+	User    string // Login user name with log_format=ENRICHED, AUID otherwise
+	UID     int
+	Comm    string
+	Exe     string
+	Keys    []string // Watch/rule keys (key=)
+
+	Argv      []string // EXECVE record
+	CWD       string   // CWD record
+	Paths     []string // PATH records
+	Proctitle string   // PROCTITLE record, arguments joined with spaces
+
+	Records []AuditRecord
+}
+
+// Exec reports whether the event is a successful execve
+func (e *AuditEvent) Exec() bool {
+	return len(e.Argv) > 0 && e.Success
+}
+
+// LoginUser reports whether the event was caused by a regular user's login
+// session, as opposed to system accounts and daemons
+func (e *AuditEvent) LoginUser() bool {
+	return e.AUID >= AuditUIDMin
+}
+
+const (
+	// AuditUIDMin is the first regular user ID (UID_MIN in login.defs)
+	AuditUIDMin = 1000
+
+	// auditUnsetID is the AUID of processes not started from a login session
+	auditUnsetID = 4294967295
+)
+
+var (
+	// type=SYSCALL msg=audit(1364481363.243:24287): arch=c000003e syscall=2 ...
+	// node=web1 type=EXECVE msg=audit(1364481363.243:24287): argc=2 a0="cat" a1="/etc/shadow"
+	auditRecordRegex = regexp.MustCompile(`type=(\S+) msg=audit\((\d+)\.(\d+):(\d+)\):\s*(.*)$`)
+
+	// a0, a1[2]
+	auditArgRegex = regexp.MustCompile(`^a(\d+)(?:\[(\d+)\])?$`)
+)
+
+// auditEncodedFields are logged quoted, or hex-encoded when they contain
+// spaces, quotes or control characters
+var auditEncodedFields = map[string]bool{
+	"comm": true, "exe": true, "cwd": true, "name": true, "proctitle": true, "key": true,
+	"acct": true, "cmd": true, "data": true, "path": true,
+}
+
+// ParseAuditRecord parses one audit log line; ok is false for other lines
+func ParseAuditRecord(line string) (AuditRecord, bool) {
+	m := auditRecordRegex.FindStringSubmatch(line)
+	if m == nil {
+		return AuditRecord{}, false
+	}
+	sec, _ := strconv.ParseInt(m[2], 10, 64)
+	ms, _ := strconv.ParseInt(m[3], 10, 64)
+	r := AuditRecord{
+		Type:   m[1],
+		ID:     m[2] + "." + m[3] + ":" + m[4],
+		Time:   time.Unix(sec, ms*int64(time.Millisecond)),
+		Fields: make(map[string]string),
+	}
+
+	// ENRICHED logs append the interpreted fields after a GS character
+	raw, enriched, _ := strings.Cut(m[5], "\x1d")
+	parseAuditFields(r.Fields, raw)
+	parseAuditFields(r.Fields, enriched)
+	return r, true
+}
+
+// parseAuditFields reads key=value pairs into fields. USER_* records nest
+// their message in msg='...', whose pairs are read too.
+func parseAuditFields(fields map[string]string, s string) {
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			return
+		}
+		key, rest := s[:eq], s[eq+1:]
+		if sp := strings.IndexByte(key, ' '); sp >= 0 { // Bare word
+			s = s[sp+1:]
+			continue
+		}
+
+		var value string
+		switch {
+		case strings.HasPrefix(rest, `"`), strings.HasPrefix(rest, `'`):
+			end := strings.IndexByte(rest[1:], rest[0])
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			value, s = rest[1:end+1], rest[min(end+2, len(rest)):]
+			if rest[0] == '\'' {
+				parseAuditFields(fields, value)
+				continue
+			}
+		default:
+			value, s, _ = strings.Cut(rest, " ")
+			if auditEncodedFields[key] || auditArgRegex.MatchString(key) {
+				value = auditUnhex(value)
+			}
+		}
+		fields[key] = value
+	}
+}
+
+// auditUnhex decodes an unquoted hex value, leaving others ("(null)", "?")
+func auditUnhex(s string) string {
+	if len(s) == 0 || len(s)%2 != 0 {
+		return s
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; !(c >= '0' && c <= '9' || c >= 'A' && c <= 'F') {
+			return s
+		}
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return s
+	}
+	return string(b)
+}
+
+// auditWindow is how many records an event may wait for its end of event
+// (EOE) record before it is emitted anyway
+const auditWindow = 64
+
+// AuditAssembler groups audit records into events by their msg=audit ID.
+// Syscall events are complete at their EOE record; other events (USER_*,
+// LOGIN, ...) are single records. It is not safe for concurrent use: each
+// file gets its own.
+type AuditAssembler struct {
+	pending map[string]*pendingAudit
+	count   int
+}
+
+type pendingAudit struct {
+	records []AuditRecord
+	first   int // Record count when the event started
+	last    int // Record count when the event was last extended
+}
+
+func NewAuditAssembler() *AuditAssembler {
+	return &AuditAssembler{pending: make(map[string]*pendingAudit)}
+}
+
+// auditSyscallRecords are the records belonging to a syscall event, which
+// ends with an EOE record
+var auditSyscallRecords = map[string]bool{
+	"SYSCALL": true, "EXECVE": true, "CWD": true, "PATH": true, "PROCTITLE": true,
+	"SOCKADDR": true, "SOCKETCALL": true, "FD_PAIR": true, "MMAP": true, "BPRM_FCAPS": true,
+	"CAPSET": true, "IPC": true, "OBJ_PID": true, "NETFILTER_CFG": true, "EOE": true,
+}
+
+// Add processes one audit log line and returns the events it completes,
+// in the order they started. Other lines are ignored.
+func (a *AuditAssembler) Add(line string) []*AuditEvent {
+	r, ok := ParseAuditRecord(line)
+	if !ok {
+		return nil
+	}
+	a.count++
+
+	var done []*AuditEvent
+	if !auditSyscallRecords[r.Type] {
+		done = append(done, newAuditEvent([]AuditRecord{r}))
+	} else if p := a.pending[r.ID]; p != nil || r.Type != "EOE" {
+		if p == nil {
+			p = &pendingAudit{first: a.count}
+			a.pending[r.ID] = p
+		}
+		p.last = a.count
+		if r.Type == "EOE" {
+			delete(a.pending, r.ID)
+			done = append(done, newAuditEvent(p.records))
+		} else {
+			p.records = append(p.records, r)
+		}
+	}
+	return append(a.expire(a.count-auditWindow), done...)
+}
+
+// Flush returns all pending events
+func (a *AuditAssembler) Flush() []*AuditEvent {
+	return a.expire(a.count)
+}
+
+// Pending returns the number of events still waiting for records
+func (a *AuditAssembler) Pending() int {
+	return len(a.pending)
+}
+
+// expire removes and returns the events last extended before record count
+// before
+func (a *AuditAssembler) expire(before int) []*AuditEvent {
+	var expired []*pendingAudit
+	for id, p := range a.pending {
+		if p.last <= before {
+			expired = append(expired, p)
+			delete(a.pending, id)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i].first < expired[j].first })
+
+	events := make([]*AuditEvent, len(expired))
+	for i, p := range expired {
+		events[i] = newAuditEvent(p.records)
+	}
+	return events
+}
+
+func newAuditEvent(records []AuditRecord) *AuditEvent {
+	e := &AuditEvent{
+		ID: records[0].ID, Time: records[0].Time, Type: records[0].Type,
+		Success: true, AUID: -1, UID: -1, Records: records,
+	}
+	for _, r := range records {
+		f := r.Fields
+		switch r.Type {
+		case "EXECVE":
+			e.Argv = auditArgv(f)
+		case "CWD":
+			e.CWD = f["cwd"]
+		case "PATH":
+			if name := f["name"]; name != "" && name != "(null)" {
+				e.Paths = append(e.Paths, name)
+			}
+		case "PROCTITLE":
+			e.Proctitle = strings.TrimSpace(strings.ReplaceAll(f["proctitle"], "\x00", " "))
+		case "SYSCALL":
+			e.Syscall = auditFirst(f["SYSCALL"], f["syscall"])
+			e.Success = f["success"] != "no"
+			e.Exit = f["exit"]
+			e.setProcess(f)
+		default:
+			if auditSyscallRecords[r.Type] {
+				continue // SOCKADDR, ...
+			}
+			if res := auditFirst(f["res"], f["success"]); res != "" {
+				e.Success = res == "success" || res == "yes" || res == "1"
+			}
+			e.setProcess(f)
+		}
+	}
+	return e
+}
+
+// setProcess reads the fields identifying the process and rule
+func (e *AuditEvent) setProcess(f map[string]string) {
+	if auid, err := strconv.Atoi(f["auid"]); err == nil && auid != auditUnsetID {
+		e.AUID = auid
+		e.User = auditFirst(f["AUID"], f["auid"])
+	}
+	if uid, err := strconv.Atoi(f["uid"]); err == nil {
+		e.UID = uid
+	}
+	e.Comm, e.Exe = f["comm"], f["exe"]
+	if key := f["key"]; key != "" && key != "(null)" {
+		// Several keys are joined by \x01 (and thus hex-encoded)
+		e.Keys = strings.Split(key, "\x01")
+	}
+}
+
+// auditArgv reassembles the arguments of an EXECVE record; long ones are
+// logged in chunks (a1_len=..., a1[0]=..., a1[1]=...)
+func auditArgv(f map[string]string) []string {
+	argc, err := strconv.Atoi(f["argc"])
+	if err != nil || argc <= 0 {
+		return nil
+	}
+	argv := make([]string, argc)
+	for i := range argv {
+		arg := "a" + strconv.Itoa(i)
+		if v, ok := f[arg]; ok {
+			argv[i] = v
+			continue
+		}
+		var b strings.Builder
+		for j := 0; ; j++ {
+			chunk, ok := f[arg+"["+strconv.Itoa(j)+"]"]
+			if !ok {
+				break
+			}
+			b.WriteString(chunk)
+		}
+		argv[i] = b.String()
+	}
+	return argv
+}
+
+func auditFirst(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
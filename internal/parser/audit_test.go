@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAuditAssembler(t *testing.T) {
+	lines := []string{
+		// execve of a hex-encoded argument, by alice (ENRICHED format)
+		`type=SYSCALL msg=audit(1700000000.123:100): arch=c000003e syscall=59 success=yes exit=0 a0=55d0 a1=55d1 items=2 ppid=900 pid=901 auid=1000 uid=0 gid=0 euid=0 tty=pts0 ses=3 comm="cat" exe="/usr/bin/cat" key="exec"` + "\x1d" + `ARCH=x86_64 SYSCALL=execve AUID="alice" UID="root"`,
+		`type=EXECVE msg=audit(1700000000.123:100): argc=3 a0="cat" a1=2F746D702F6D792066696C65 a2_len=6 a2[0]="ab" a2[1]=63642065`,
+		`type=CWD msg=audit(1700000000.123:100): cwd="/home/alice"`,
+		`type=PATH msg=audit(1700000000.123:100): item=0 name="/usr/bin/cat" inode=1 nametype=NORMAL`,
+		// An unrelated single-record event in the middle
+		`type=USER_LOGIN msg=audit(1700000000.200:102): pid=700 uid=0 auid=1001 ses=4 msg='op=login acct="bob" exe="/usr/sbin/sshd" hostname=? addr=1.2.3.4 terminal=ssh res=failed'`,
+		`type=PROCTITLE msg=audit(1700000000.123:100): proctitle=636174002F746D702F6D792066696C65`,
+		`type=EOE msg=audit(1700000000.123:100): `,
+		// Failed open of a watched file by a daemon
+		`node=web1 type=SYSCALL msg=audit(1700000001.000:101): arch=c000003e syscall=257 success=no exit=-13 auid=4294967295 uid=33 comm="php-fpm" exe="/usr/sbin/php-fpm" key=737368645F636F6E66696701736563726574`,
+		`type=PATH msg=audit(1700000001.000:101): item=0 name=(null) nametype=UNKNOWN`,
+		"Dec 12 14:00:00 web1 kernel: not an audit line",
+	}
+
+	a := NewAuditAssembler()
+	var events []*AuditEvent
+	for _, line := range lines {
+		events = append(events, a.Add(line)...)
+	}
+	if len(events) != 2 || a.Pending() != 1 {
+		t.Fatalf("got %d events (%d pending) before flush; want 2 (1 pending)", len(events), a.Pending())
+	}
+	events = append(events, a.Flush()...)
+	if len(events) != 3 {
+		t.Fatalf("got %d events; want 3", len(events))
+	}
+
+	login, exec, failed := events[0], events[1], events[2]
+	if login.Type != "USER_LOGIN" || login.Success || login.User != "1001" || login.Exe != "/usr/sbin/sshd" || !login.LoginUser() {
+		t.Errorf("login = %+v", login)
+	}
+
+	if exec.ID != "1700000000.123:100" || exec.Time.Unix() != 1700000000 || exec.Time.Nanosecond() != 123000000 {
+		t.Errorf("exec ID = %q, Time = %v", exec.ID, exec.Time)
+	}
+	if !exec.Exec() || !exec.LoginUser() || exec.User != "alice" || exec.UID != 0 || exec.Syscall != "execve" {
+		t.Errorf("exec = %+v", exec)
+	}
+	if want := []string{"cat", "/tmp/my file", "abcd e"}; !reflect.DeepEqual(exec.Argv, want) {
+		t.Errorf("Argv = %q; want %q", exec.Argv, want)
+	}
+	if exec.CWD != "/home/alice" || exec.Proctitle != "cat /tmp/my file" || !reflect.DeepEqual(exec.Paths, []string{"/usr/bin/cat"}) {
+		t.Errorf("CWD = %q, Proctitle = %q, Paths = %q", exec.CWD, exec.Proctitle, exec.Paths)
+	}
+	if !reflect.DeepEqual(exec.Keys, []string{"exec"}) || len(exec.Records) != 5 {
+		t.Errorf("Keys = %q, %d records", exec.Keys, len(exec.Records))
+	}
+
+	if failed.Success || failed.Exit != "-13" || failed.Syscall != "257" || failed.AUID != -1 || failed.LoginUser() || failed.Exec() {
+		t.Errorf("failed = %+v", failed)
+	}
+	if !reflect.DeepEqual(failed.Keys, []string{"sshd_config", "secret"}) || failed.Paths != nil {
+		t.Errorf("Keys = %q, Paths = %q", failed.Keys, failed.Paths)
+	}
+}
@@ -1,8 +1,10 @@
 package parser
 
 import (
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -16,13 +18,23 @@ type CaddyJSONEntry struct {
 	Msg     string  `json:"msg"`
 	Request struct {
 		RemoteIP  string              `json:"remote_ip"`
+		ClientIP  string              `json:"client_ip"` // Behind trusted proxies (Caddy 2.7+)
 		Method    string              `json:"method"`
+		Host      string              `json:"host"`
 		URI       string              `json:"uri"`
 		Proto     string              `json:"proto"`
 		Headers   map[string][]string `json:"headers"`
+		TLS       *struct {
+			Version     uint16 `json:"version"`
+			CipherSuite uint16 `json:"cipher_suite"`
+			ServerName  string `json:"server_name"`
+		} `json:"tls"`
 	} `json:"request"`
-	Status int `json:"status"`
-	Size   int `json:"size"` // Response size
+	UserID    string  `json:"user_id"`
+	Duration  float64 `json:"duration"`   // Seconds
+	BytesRead int     `json:"bytes_read"` // Request body size
+	Status    int     `json:"status"`
+	Size      int     `json:"size"` // Response size
 }
 
 func (p *CaddyParser) Parse(line string) (*GenericLogEntry, error) {
@@ -44,10 +56,15 @@ func (p *CaddyParser) Parse(line string) (*GenericLogEntry, error) {
 		userAgent = v[0]
 	}
 
-	return &GenericLogEntry{
+	remoteUser := "-" // Auth user not always in standard json structure easily
+	if entry.UserID != "" {
+		remoteUser = entry.UserID
+	}
+
+	result := &GenericLogEntry{
 		Service:       "caddy",
 		RemoteIP:      entry.Request.RemoteIP,
-		RemoteUser:    "-", // Auth user not always in standard json structure easily
+		RemoteUser:    remoteUser,
 		TimeLocal:     t,
 		Method:        entry.Request.Method,
 		Path:          entry.Request.URI,
@@ -56,5 +73,28 @@ func (p *CaddyParser) Parse(line string) (*GenericLogEntry, error) {
 		BodyBytesSent: entry.Size,
 		Referer:       referer,
 		UserAgent:     userAgent,
-	}, nil
+		Latency:       entry.Duration,
+		Host:          entry.Request.Host,
+		RequestBytes:  entry.BytesRead,
+	}
+	if v := entry.Request.Headers["X-Forwarded-For"]; len(v) > 0 {
+		result.ForwardedFor = splitForwardedFor(strings.Join(v, ","))
+	}
+	if v := entry.Request.Headers["X-Request-Id"]; len(v) > 0 {
+		result.RequestID = v[0]
+	}
+	if tlsInfo := entry.Request.TLS; tlsInfo != nil {
+		result.TLSProtocol = tls.VersionName(tlsInfo.Version)
+		result.TLSCipher = tls.CipherSuiteName(tlsInfo.CipherSuite)
+		if tlsInfo.ServerName != "" {
+			result.SetExtra("ssl_server_name", tlsInfo.ServerName)
+		}
+	}
+	if entry.Request.ClientIP != "" && entry.Request.ClientIP != entry.Request.RemoteIP {
+		result.SetExtra("client_ip", entry.Request.ClientIP)
+	}
+	if entry.Logger != "" {
+		result.SetExtra("logger", entry.Logger)
+	}
+	return result, nil
 }
@@ -0,0 +1,120 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CloudFrontParser reads CloudFront standard logs (W3C, tab separated,
+// fields in the documented fixed order). "#Version" and "#Fields" header
+// lines are reported as errors.
+type CloudFrontParser struct{}
+
+// CloudFront standard log fields
+// 2019-12-04	21:02:31	LAX1	392	192.0.2.100	GET	d111111abcdef8.cloudfront.net	/index.html	200	-	Mozilla/5.0%20(...)	-	-	Hit	SOX4xwn4...==
+// d111111abcdef8.cloudfront.net	https	23	0.001	-	TLSv1.2	ECDHE-RSA-AES128-GCM-SHA256	Hit	HTTP/2.0	-	-	11040	0.001	Hit	text/html	78	-	-
+const (
+	cfDate = iota
+	cfTime
+	cfEdgeLocation // x-edge-location, e.g. "LAX1"
+	cfBytesSent
+	cfClientIP
+	cfMethod
+	cfDistribution // cs(Host): the CloudFront domain
+	cfURIStem
+	cfStatus
+	cfReferer
+	cfUserAgent
+	cfURIQuery
+	cfCookie
+	cfResultType
+	cfRequestID
+	cfHostHeader
+	cfScheme
+	cfBytesReceived
+	cfTimeTaken
+	cfForwardedFor
+	cfTLSProtocol
+	cfTLSCipher
+	cfResponseResultType
+	cfProtocolVersion
+	cfFLEStatus
+	cfFLEFields
+	cfClientPort
+	cfTimeToFirstByte
+	cfDetailedResultType
+	cfContentType
+	cfContentLength
+)
+
+// cloudFrontExtras are the CloudFront values kept in Extra
+var cloudFrontExtras = map[int]string{
+	cfEdgeLocation:       "edge_location",
+	cfDistribution:       "distribution",
+	cfScheme:             "scheme",
+	cfResultType:         "edge_result_type",
+	cfResponseResultType: "edge_response_result_type",
+	cfDetailedResultType: "edge_detailed_result_type",
+	cfTimeToFirstByte:    "time_to_first_byte",
+	cfContentType:        "sent_http_content_type",
+}
+
+func (p *CloudFrontParser) Parse(line string) (*GenericLogEntry, error) {
+	fields := strings.Split(line, "\t")
+	if strings.HasPrefix(line, "#") || len(fields) <= cfTimeTaken {
+		return nil, fmt.Errorf("failed to parse cloudfront line: %s", line)
+	}
+	t, err := time.Parse("2006-01-02 15:04:05", fields[cfDate]+" "+fields[cfTime])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse cloudfront line: %s", line)
+	}
+	get := func(i int) string {
+		if i < len(fields) {
+			return dash(fields[i])
+		}
+		return ""
+	}
+
+	status, _ := strconv.Atoi(fields[cfStatus])
+	sent, _ := strconv.Atoi(fields[cfBytesSent])
+	received, _ := strconv.Atoi(fields[cfBytesReceived])
+	latency, _ := strconv.ParseFloat(fields[cfTimeTaken], 64)
+
+	path := fields[cfURIStem]
+	if query := get(cfURIQuery); query != "" {
+		path += "?" + query
+	}
+	host := get(cfHostHeader)
+	if host == "" {
+		host = get(cfDistribution)
+	}
+
+	entry := &GenericLogEntry{
+		Service:       "cloudfront",
+		RemoteIP:      fields[cfClientIP],
+		RemoteUser:    "-",
+		TimeLocal:     t,
+		Method:        fields[cfMethod],
+		Path:          path,
+		Protocol:      get(cfProtocolVersion),
+		Status:        status,
+		BodyBytesSent: sent,
+		Referer:       w3cUnescape(get(cfReferer)),
+		UserAgent:     w3cUnescape(get(cfUserAgent)),
+		Latency:       latency,
+		Host:          host,
+		RequestID:     get(cfRequestID),
+		RequestBytes:  received,
+		ForwardedFor:  splitForwardedFor(w3cUnescape(get(cfForwardedFor))),
+		TLSProtocol:   get(cfTLSProtocol),
+		TLSCipher:     get(cfTLSCipher),
+	}
+	for i, key := range cloudFrontExtras {
+		if v := get(i); v != "" {
+			entry.SetExtra(key, v)
+		}
+	}
+	return entry, nil
+}
@@ -0,0 +1,44 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCloudFrontParser(t *testing.T) {
+	line := strings.Join([]string{
+		"2019-12-04", "21:02:31", "LAX1", "392", "192.0.2.100", "GET", "d111111abcdef8.cloudfront.net", "/index.html", "200", "-",
+		"Mozilla/5.0%20(Windows%20NT%2010.0;%20Win64;%20x64)", "q=1", "-", "Hit", "SOX4xwn4XV6Q4rgb7XiVGOHms_BGlTAC4KyHmureZmBNrjGdRLiNIQ==",
+		"www.example.com", "https", "23", "0.25", "203.0.113.7,%20192.0.2.100", "TLSv1.2", "ECDHE-RSA-AES128-GCM-SHA256", "Hit", "HTTP/2.0",
+		"-", "-", "11040", "0.125", "Hit", "text/html", "78", "-", "-",
+	}, "\t")
+
+	got, err := (&CloudFrontParser{}).Parse(line)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if want := time.Date(2019, 12, 4, 21, 2, 31, 0, time.UTC); !got.TimeLocal.Equal(want) {
+		t.Errorf("TimeLocal = %v; want %v", got.TimeLocal, want)
+	}
+	extra := got.Extra
+	got.TimeLocal, got.Service, got.Extra = time.Time{}, "", nil
+	want := GenericLogEntry{RemoteIP: "192.0.2.100", RemoteUser: "-", Method: "GET", Path: "/index.html?q=1", Protocol: "HTTP/2.0", Status: 200, BodyBytesSent: 392,
+		UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64)", Latency: 0.25, Host: "www.example.com", RequestID: "SOX4xwn4XV6Q4rgb7XiVGOHms_BGlTAC4KyHmureZmBNrjGdRLiNIQ==",
+		RequestBytes: 23, ForwardedFor: []string{"203.0.113.7", "192.0.2.100"}, TLSProtocol: "TLSv1.2", TLSCipher: "ECDHE-RSA-AES128-GCM-SHA256"}
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("Parse() = %+v; want %+v", *got, want)
+	}
+	wantExtra := map[string]string{
+		"edge_location": "LAX1", "distribution": "d111111abcdef8.cloudfront.net", "scheme": "https", "edge_result_type": "Hit",
+		"edge_response_result_type": "Hit", "edge_detailed_result_type": "Hit", "time_to_first_byte": "0.125", "sent_http_content_type": "text/html",
+	}
+	if !reflect.DeepEqual(map[string]string(extra), wantExtra) {
+		t.Errorf("Parse() extra = %v; want %v", extra, wantExtra)
+	}
+
+	if _, err := (&CloudFrontParser{}).Parse("#Version: 1.0"); err == nil {
+		t.Error("Parse(header) error = nil; want error")
+	}
+}
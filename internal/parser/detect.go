@@ -0,0 +1,160 @@
+package parser
+
+import "sync"
+
+// detectable are the registered parsers tried by AutoParser, most specific
+// first (nginx and apache accept the same combined format; nginx wins ties,
+// and ingress-nginx lines start with it)
+var detectable = []string{"ingress-nginx", "nginx", "apache", "caddy", "traefik", "envoy", "haproxy", "tomcat", "lighttpd", "alb", "cloudfront", "gcp_lb", "json", "logfmt"}
+
+const (
+	detectSampleLines = 20  // Lines scored before a format is chosen
+	recheckWindow     = 200 // Lines between error rate checks
+	recheckErrorRate  = 0.5 // Error rate that triggers detection again
+)
+
+// AutoParser detects the log format from the lines themselves. The first
+// lines are parsed by every candidate and scored on how much of the entry
+// they fill in; once the sample is complete the best one is used alone,
+// until its error rate climbs (e.g. the server's log format was changed).
+// Use one AutoParser per file.
+type AutoParser struct {
+	// OnDetect, if set, is called with the chosen parser name whenever the
+	// detected format changes
+	OnDetect func(format string)
+
+	mu         sync.Mutex
+	candidates []autoCandidate
+	current    int // Index into candidates, -1 while sampling
+	detected   string
+	sampled    int
+	lines      int // Since the last error rate check
+	errors     int
+}
+
+type autoCandidate struct {
+	name  string
+	p     LogParser
+	score int
+}
+
+// NewAutoParser returns a detecting parser. preferred (e.g. the discovered
+// service name) is tried first and wins ties; it may be empty or unknown.
+func NewAutoParser(preferred string) *AutoParser {
+	names := detectable
+	if _, ok := registry[preferred]; ok {
+		names = append([]string{preferred}, detectable...)
+	}
+	a := &AutoParser{current: -1}
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		a.candidates = append(a.candidates, autoCandidate{name: name, p: registry[name]()})
+	}
+	return a
+}
+
+// Format returns the detected parser name, or "" while detecting
+func (a *AutoParser) Format() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.detected
+}
+
+// Parse implements LogParser
+func (a *AutoParser) Parse(line string) (*GenericLogEntry, error) {
+	a.mu.Lock()
+	if a.current < 0 {
+		a.mu.Unlock()
+		return a.sample(line)
+	}
+	p := a.candidates[a.current].p
+	a.mu.Unlock()
+
+	entry, err := p.Parse(line)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.lines++
+	if err != nil {
+		a.errors++
+	}
+	if a.lines >= recheckWindow {
+		if float64(a.errors)/float64(a.lines) >= recheckErrorRate {
+			a.current, a.sampled = -1, 0 // Detect again
+			for i := range a.candidates {
+				a.candidates[i].score = 0
+			}
+		}
+		a.lines, a.errors = 0, 0
+	}
+	return entry, err
+}
+
+// sample parses line with every candidate, adds their scores and returns
+// the result that scored best on this line. Lines are parsed without mu
+// held, only the scores are merged under it.
+func (a *AutoParser) sample(line string) (*GenericLogEntry, error) {
+	type result struct {
+		entry *GenericLogEntry
+		err   error
+		score int
+	}
+	results := make([]result, len(a.candidates))
+	lineBest := 0
+	for i := range a.candidates {
+		entry, err := a.candidates[i].p.Parse(line) // p never changes, score needs mu
+		results[i] = result{entry, err, entryScore(entry, err)}
+		if results[i].score > results[lineBest].score {
+			lineBest = i
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.current >= 0 {
+		return results[lineBest].entry, results[lineBest].err // Detected meanwhile
+	}
+	best := 0
+	for i := range a.candidates {
+		c := &a.candidates[i]
+		c.score += results[i].score
+		if c.score > a.candidates[best].score {
+			best = i
+		}
+	}
+
+	a.sampled++
+	if a.sampled >= detectSampleLines {
+		if a.candidates[best].score > 0 {
+			a.current = best
+			if name := a.candidates[best].name; name != a.detected {
+				a.detected = name
+				if a.OnDetect != nil {
+					a.OnDetect(name)
+				}
+			}
+		} else {
+			a.sampled = 0 // Nothing matches (yet), keep sampling
+		}
+	}
+	return results[lineBest].entry, results[lineBest].err
+}
+
+// entryScore rates a parse result by the request fields it filled in, so
+// lenient parsers do not beat the one that really understands the format
+func entryScore(e *GenericLogEntry, err error) int {
+	if err != nil || e == nil {
+		return 0
+	}
+	score := 1
+	for _, set := range []bool{e.Status > 0, e.Method != "", e.Path != "", e.RemoteIP != "", !e.TimeLocal.IsZero()} {
+		if set {
+			score++
+		}
+	}
+	return score
+}
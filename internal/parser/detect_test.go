@@ -0,0 +1,93 @@
+package parser
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestAutoParser(t *testing.T) {
+	tests := []struct {
+		name      string
+		preferred string
+		line      string
+		want      string
+	}{
+		{"Combined", "", `10.0.0.1 - - [12/Dec/2023:14:00:00 +0000] "GET /a HTTP/1.1" 200 512 "-" "curl/8.0"`, "nginx"},
+		{"Combined, discovered as apache", "apache", `10.0.0.1 - - [12/Dec/2023:14:00:00 +0000] "GET /a HTTP/1.1" 200 512 "-" "curl/8.0"`, "apache"},
+		{"nginx JSON", "nginx", `{"time_iso8601":"2023-12-12T14:00:00+00:00","remote_addr":"10.0.0.1","request":"GET /a HTTP/1.1","status":"200","body_bytes_sent":"512"}`, "json"},
+		{"Caddy", "", `{"level":"info","ts":1702389600.5,"msg":"handled request","request":{"remote_ip":"10.0.0.1","method":"GET","host":"x","uri":"/a","proto":"HTTP/2.0"},"duration":0.01,"size":512,"status":200}`, "caddy"},
+		{"logfmt", "", `method=GET path=/a status=200 duration=12ms fwd="10.0.0.1"`, "logfmt"},
+		{"ingress-nginx", "", `10.0.0.1 - - [12/Dec/2023:14:00:00 +0000] "GET /a HTTP/1.1" 200 512 "-" "curl/8.0" 54 0.012 [shop-web-80] [] 10.244.1.7:8080 512 0.010 200 5d0c4d1b`, "ingress-nginx"},
+		{"ALB", "", `https 2018-07-02T22:23:00.186641Z app/my-lb/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.086 0.048 0.037 200 200 0 57 "GET https://www.example.com:443/ HTTP/1.1" "curl/7.46.0" ECDHE-RSA-AES128-GCM-SHA256 TLSv1.2 arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 "Root=1-58337281-1d84f3d73c47ec4e58577259" "www.example.com" "-" 1 2018-07-02T22:22:48.364000Z "forward" "-" "-" "10.0.0.1:80" "200" "-" "-"`, "alb"},
+		{"GCP load balancer", "", `{"httpRequest":{"requestMethod":"GET","requestUrl":"https://x/a","status":200,"remoteIp":"10.0.0.1","latency":"0.01s"},"timestamp":"2023-12-12T14:00:00Z"}`, "gcp_lb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewAutoParser(tt.preferred)
+			var detected []string
+			p.OnDetect = func(format string) { detected = append(detected, format) }
+			for i := 0; i < detectSampleLines; i++ {
+				if _, err := p.Parse(tt.line); err != nil {
+					t.Fatalf("Parse() error = %v", err)
+				}
+			}
+			if got := p.Format(); got != tt.want || len(detected) != 1 {
+				t.Errorf("Format() = %q (OnDetect %v); want %q", got, detected, tt.want)
+			}
+		})
+	}
+}
+
+func TestAutoParserRedetects(t *testing.T) {
+	p := NewAutoParser("")
+	for i := 0; i < detectSampleLines; i++ {
+		p.Parse(`10.0.0.1 - - [12/Dec/2023:14:00:00 +0000] "GET /a HTTP/1.1" 200 512 "-" "curl/8.0"`)
+	}
+	if p.Format() != "nginx" {
+		t.Fatalf("Format() = %q; want nginx", p.Format())
+	}
+
+	// The server switched to logfmt: errors until the next check, then detect again
+	for i := 0; i < recheckWindow+detectSampleLines; i++ {
+		p.Parse(fmt.Sprintf(`method=GET path=/%d status=200`, i))
+	}
+	if p.Format() != "logfmt" {
+		t.Errorf("Format() = %q after format change; want logfmt", p.Format())
+	}
+}
+
+func TestAutoParserSampleReturnsLineResult(t *testing.T) {
+	p := NewAutoParser("")
+	for i := 0; i < detectSampleLines/2; i++ {
+		p.Parse(`10.0.0.1 - - [12/Dec/2023:14:00:00 +0000] "GET /a HTTP/1.1" 200 512 "-" "curl/8.0"`)
+	}
+
+	// nginx leads the sample but cannot parse this line; logfmt can
+	got, err := p.Parse(`method=POST path=/b status=201`)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got.Method != "POST" || got.Status != 201 {
+		t.Errorf("Parse() = %+v; want the logfmt result", got)
+	}
+}
+
+func TestAutoParserConcurrentSampling(t *testing.T) {
+	p := NewAutoParser("")
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < detectSampleLines; j++ {
+				p.Parse(`10.0.0.1 - - [12/Dec/2023:14:00:00 +0000] "GET /a HTTP/1.1" 200 512 "-" "curl/8.0"`)
+			}
+		}()
+	}
+	wg.Wait()
+	if p.Format() != "nginx" {
+		t.Errorf("Format() = %q; want nginx", p.Format())
+	}
+}
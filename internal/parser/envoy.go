@@ -1,9 +1,11 @@
 package parser
 
 import (
+	"encoding/json"
 	"fmt"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -12,34 +14,176 @@ type EnvoyParser struct{}
 // Envoy Default Access Log Format
 // [START_TIME] "METHOD PATH PROTOCOL" RESPONSE_CODE RESPONSE_FLAGS BYTES_RECEIVED BYTES_SENT DURATION X-ENVOY-UPSTREAM-SERVICE-TIME "X-FORWARDED-FOR" "USER-AGENT" "REQUEST_ID" "AUTHORITY" "UPSTREAM_HOST"
 // [2016-04-15T20:17:00.310Z] "POST /api/v1/locations HTTP/1.1" 204 - 154 0 226 100 "10.0.35.16" "Mozilla/5.0" "v23-234-234" "authority" "10.0.35.16:8080"
-var envoyRegex = regexp.MustCompile(`^\[([^\]]+)\] "(\S+) (\S+) (\S+)" (\d+) \S+ (\d+) (\d+) \S+ \S+ "([^"]*)" "([^"]*)"`)
+// Groups: 1 time, 2-4 request, 5 status, 6 flags, 7 bytes received, 8 bytes sent,
+// 9 duration (ms), 10 upstream service time (ms), 11 XFF, 12 UA, 13 request id, 14 authority, 15 upstream host
+var envoyRegex = regexp.MustCompile(`^\[([^\]]+)\] "(\S+) (\S+) (\S+)" (\d+) (\S+) (\d+) (\d+) (\d+|-) (\d+|-) "([^"]*)" "([^"]*)"(?: "([^"]*)" "([^"]*)" "([^"]*)")?`)
+
+// Istio's default format adds details after the flags and connection
+// details at the end:
+// [START_TIME] "METHOD PATH PROTOCOL" RESPONSE_CODE RESPONSE_FLAGS RESPONSE_CODE_DETAILS CONNECTION_TERMINATION_DETAILS "UPSTREAM_TRANSPORT_FAILURE_REASON"
+// BYTES_RECEIVED BYTES_SENT DURATION X-ENVOY-UPSTREAM-SERVICE-TIME "X-FORWARDED-FOR" "USER-AGENT" "REQUEST_ID" "AUTHORITY" "UPSTREAM_HOST"
+// UPSTREAM_CLUSTER UPSTREAM_LOCAL_ADDRESS DOWNSTREAM_LOCAL_ADDRESS DOWNSTREAM_REMOTE_ADDRESS REQUESTED_SERVER_NAME ROUTE_NAME
+// Groups: 1 time, 2-4 request, 5 status, 6 flags, 7 code details, 8 termination details, 9 transport failure,
+// 10 bytes received, 11 bytes sent, 12 duration, 13 upstream service time, 14 XFF, 15 UA, 16 request id,
+// 17 authority, 18 upstream host, 19 cluster, 20 upstream local, 21 downstream local, 22 downstream remote, 23 SNI, 24 route
+var istioRegex = regexp.MustCompile(`^\[([^\]]+)\] "(\S+) (\S+) (\S+)" (\d+) (\S+) (\S+) (\S+) "([^"]*)" (\d+) (\d+) (\d+|-) (\d+|-) "([^"]*)" "([^"]*)" "([^"]*)" "([^"]*)" "([^"]*)" (\S+) (\S+) (\S+) (\S+) (\S+) (\S+)`)
+
+// envoyExtras are the values kept in Extra, in Istio regex group order
+var envoyExtras = map[int]string{
+	7:  "response_code_details",
+	8:  "connection_termination_details",
+	9:  "upstream_transport_failure_reason",
+	19: "upstream_cluster",
+	20: "upstream_local_address",
+	21: "downstream_local_address",
+	22: "downstream_remote_address",
+	23: "requested_server_name",
+	24: "route_name",
+}
 
 func (p *EnvoyParser) Parse(line string) (*GenericLogEntry, error) {
+	if strings.HasPrefix(line, "{") {
+		return p.parseJSON(line)
+	}
+	if m := istioRegex.FindStringSubmatch(line); m != nil {
+		entry := envoyEntry(m[1], m[2], m[3], m[4], m[5], m[6], m[10], m[11], m[12], m[13], m[14], m[15], m[16], m[17], m[18])
+		for i, key := range envoyExtras {
+			if v := dash(m[i]); v != "" {
+				entry.SetExtra(key, v)
+			}
+		}
+		if entry.RemoteIP == "" {
+			entry.RemoteIP = stripPort(dash(m[22]))
+		}
+		return entry, nil
+	}
+
 	matches := envoyRegex.FindStringSubmatch(line)
 	if matches == nil {
 		return nil, fmt.Errorf("failed to parse envoy line: %s", line)
 	}
+	return envoyEntry(matches[1], matches[2], matches[3], matches[4], matches[5], matches[6], matches[7], matches[8],
+		matches[9], matches[10], matches[11], matches[12], matches[13], matches[14], matches[15]), nil
+}
 
+// envoyEntry builds an entry from the default format's fields
+func envoyEntry(start, method, path, protocol, status, flags, received, sent, duration, upstreamTime, xff, ua, requestID, authority, upstreamHost string) *GenericLogEntry {
 	// Time parsing: 2016-04-15T20:17:00.310Z
-	t, err := time.Parse(time.RFC3339, matches[1])
+	t, err := time.Parse(time.RFC3339, start)
 	if err != nil {
 		t = time.Now()
 	}
 
-	status, _ := strconv.Atoi(matches[5])
-	bytesSent, _ := strconv.Atoi(matches[7])
-
-	return &GenericLogEntry{
-		Service:       "envoy",
-		RemoteIP:      matches[8], // X-Forwarded-For usually
-		RemoteUser:    "-", 
-		TimeLocal:     t,
-		Method:        matches[2],
-		Path:          matches[3],
-		Protocol:      matches[4],
-		Status:        status,
-		BodyBytesSent: bytesSent,
-		Referer:       "",
-		UserAgent:     matches[9],
-	}, nil
+	code, _ := strconv.Atoi(status)
+	bytesReceived, _ := strconv.Atoi(received)
+	bytesSent, _ := strconv.Atoi(sent)
+	durationMs, _ := strconv.ParseFloat(duration, 64)
+	upstreamMs, _ := strconv.ParseFloat(upstreamTime, 64)
+
+	// X-Forwarded-For usually carries the client
+	forwardedFor := splitForwardedFor(xff)
+	remoteIP := ""
+	if len(forwardedFor) > 0 {
+		remoteIP = forwardedFor[0]
+	}
+
+	entry := &GenericLogEntry{
+		Service:         "envoy",
+		RemoteIP:        remoteIP,
+		RemoteUser:      "-",
+		TimeLocal:       t,
+		Method:          method,
+		Path:            path,
+		Protocol:        protocol,
+		Status:          code,
+		BodyBytesSent:   bytesSent,
+		Referer:         "",
+		UserAgent:       dash(ua),
+		Latency:         durationMs / 1000, // DURATION: first downstream byte in to last byte out
+		RequestBytes:    bytesReceived,
+		ForwardedFor:    forwardedFor,
+		UpstreamLatency: upstreamMs / 1000,
+		RequestID:       dash(requestID),
+		Host:            dash(authority),
+		UpstreamAddr:    dash(upstreamHost),
+	}
+	if flags != "-" {
+		entry.SetExtra("response_flags", flags)
+	}
+	return entry
+}
+
+// envoyJSONKeys are the usual keys of JSON access logs (Istio's
+// EnvoyFileAccessLog JSON encoding and the Envoy documentation examples)
+var envoyJSONKeys = struct {
+	start, method, path, protocol, status, flags, received, sent, duration, upstreamTime, xff, ua, requestID, authority, upstreamHost, remote []string
+}{
+	start:        []string{"start_time", "timestamp"},
+	method:       []string{"method"},
+	path:         []string{"path"},
+	protocol:     []string{"protocol"},
+	status:       []string{"response_code", "status"},
+	flags:        []string{"response_flags"},
+	received:     []string{"bytes_received"},
+	sent:         []string{"bytes_sent"},
+	duration:     []string{"duration"},
+	upstreamTime: []string{"upstream_service_time", "x_envoy_upstream_service_time"},
+	xff:          []string{"x_forwarded_for"},
+	ua:           []string{"user_agent"},
+	requestID:    []string{"request_id", "x_request_id"},
+	authority:    []string{"authority"},
+	upstreamHost: []string{"upstream_host"},
+	remote:       []string{"downstream_remote_address", "downstream_direct_remote_address"},
+}
+
+func (p *EnvoyParser) parseJSON(line string) (*GenericLogEntry, error) {
+	dec := json.NewDecoder(strings.NewReader(line))
+	dec.UseNumber()
+	var doc map[string]interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse envoy json: %v", err)
+	}
+
+	get := func(keys []string) string {
+		for _, key := range keys {
+			if v, ok := doc[key]; ok && v != nil {
+				return jsonString(v)
+			}
+		}
+		return "-"
+	}
+	k := envoyJSONKeys
+	status := get(k.status)
+	if status == "-" {
+		return nil, fmt.Errorf("not an envoy access log line: %s", line)
+	}
+
+	entry := envoyEntry(get(k.start), get(k.method), get(k.path), get(k.protocol), status, get(k.flags), get(k.received),
+		get(k.sent), get(k.duration), get(k.upstreamTime), get(k.xff), get(k.ua), get(k.requestID), get(k.authority), get(k.upstreamHost))
+	for _, key := range envoyExtras {
+		if v := dash(get([]string{key})); v != "" {
+			entry.SetExtra(key, v)
+		}
+	}
+	if entry.RemoteIP == "" {
+		entry.RemoteIP = stripPort(dash(get(k.remote)))
+	}
+	return entry, nil
+}
+
+// EnvoyResponseFlags splits RESPONSE_FLAGS ("UH", "UF,URX", "-") into
+// individual flags
+func EnvoyResponseFlags(flags string) []string {
+	if flags == "" || flags == "-" {
+		return nil
+	}
+	return strings.Split(flags, ",")
+}
+
+// dash maps the "-" placeholder for a missing value to ""
+func dash(value string) string {
+	if value == "-" {
+		return ""
+	}
+	return value
 }
@@ -0,0 +1,78 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEnvoyParser(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		want  GenericLogEntry
+		extra map[string]string
+		time  time.Time
+	}{
+		{
+			"Default format",
+			`[2016-04-15T20:17:00.310Z] "POST /api/v1/locations HTTP/1.1" 204 - 154 0 226 100 "10.0.35.16" "Mozilla/5.0" "v23-234-234" "api.example.com" "10.0.35.16:8080"`,
+			GenericLogEntry{RemoteIP: "10.0.35.16", RemoteUser: "-", Method: "POST", Path: "/api/v1/locations", Protocol: "HTTP/1.1", Status: 204, UserAgent: "Mozilla/5.0",
+				Latency: 0.226, RequestBytes: 154, ForwardedFor: []string{"10.0.35.16"}, UpstreamLatency: 0.1, RequestID: "v23-234-234", Host: "api.example.com", UpstreamAddr: "10.0.35.16:8080"},
+			nil,
+			time.Date(2016, 4, 15, 20, 17, 0, 310000000, time.UTC),
+		},
+		{
+			"Istio format",
+			`[2020-11-25T21:26:18.409Z] "GET /status/418 HTTP/1.1" 503 UF,URX upstream_reset_before_response_started{connection_failure} - "-" 0 91 4 - "-" "curl/7.73.0" "84961386" "httpbin:8000" "10.44.1.27:80" outbound|8000||httpbin.foo.svc.cluster.local - 10.0.45.184:8000 10.44.1.23:46520 - default`,
+			GenericLogEntry{RemoteIP: "10.44.1.23", RemoteUser: "-", Method: "GET", Path: "/status/418", Protocol: "HTTP/1.1", Status: 503, BodyBytesSent: 91, UserAgent: "curl/7.73.0",
+				Latency: 0.004, RequestID: "84961386", Host: "httpbin:8000", UpstreamAddr: "10.44.1.27:80"},
+			map[string]string{
+				"response_flags":            "UF,URX",
+				"response_code_details":     "upstream_reset_before_response_started{connection_failure}",
+				"upstream_cluster":          "outbound|8000||httpbin.foo.svc.cluster.local",
+				"downstream_local_address":  "10.0.45.184:8000",
+				"downstream_remote_address": "10.44.1.23:46520",
+				"route_name":                "default",
+			},
+			time.Date(2020, 11, 25, 21, 26, 18, 409000000, time.UTC),
+		},
+		{
+			"JSON format",
+			`{"start_time":"2023-12-12T14:00:00.000Z","method":"GET","path":"/a","protocol":"HTTP/2","response_code":200,"response_flags":"-","bytes_received":0,"bytes_sent":512,"duration":12,"upstream_service_time":"10","x_forwarded_for":null,"user_agent":"curl/8.0","request_id":"abc","authority":"web","upstream_host":"10.0.0.2:8080","upstream_cluster":"web","downstream_remote_address":"10.0.0.1:5000"}`,
+			GenericLogEntry{RemoteIP: "10.0.0.1", RemoteUser: "-", Method: "GET", Path: "/a", Protocol: "HTTP/2", Status: 200, BodyBytesSent: 512, UserAgent: "curl/8.0",
+				Latency: 0.012, UpstreamLatency: 0.01, RequestID: "abc", Host: "web", UpstreamAddr: "10.0.0.2:8080"},
+			map[string]string{"upstream_cluster": "web", "downstream_remote_address": "10.0.0.1:5000"},
+			time.Date(2023, 12, 12, 14, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := (&EnvoyParser{}).Parse(tt.line)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !got.TimeLocal.Equal(tt.time) {
+				t.Errorf("TimeLocal = %v; want %v", got.TimeLocal, tt.time)
+			}
+			extra := got.Extra
+			got.TimeLocal, got.Service, got.Extra = time.Time{}, "", nil
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Parse() = %+v; want %+v", *got, tt.want)
+			}
+			if len(extra) != len(tt.extra) {
+				t.Errorf("Parse() extra = %v; want %v", extra, tt.extra)
+			}
+			for k, v := range tt.extra {
+				if extra[k] != v {
+					t.Errorf("Extra[%q] = %q; want %q", k, extra[k], v)
+				}
+			}
+		})
+	}
+
+	if _, err := (&EnvoyParser{}).Parse(`{"level":"info","msg":"not an access log"}`); err == nil {
+		t.Error("Parse(other JSON) error = nil; want error")
+	}
+}
@@ -0,0 +1,168 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrorCategory classifies web server error log events
+type ErrorCategory string
+
+const (
+	ErrUpstreamTimeout   ErrorCategory = "upstream_timeout"
+	ErrClientTimeout     ErrorCategory = "client_timeout"
+	ErrConnectionRefused ErrorCategory = "connection_refused"
+	ErrUpstreamFailure   ErrorCategory = "upstream_failure" // Other upstream errors (reset, premature close, no live upstreams)
+	ErrLimitReq          ErrorCategory = "limit_req"
+	ErrLimitConn         ErrorCategory = "limit_conn"
+	ErrSSLHandshake      ErrorCategory = "ssl_handshake"
+	ErrModSecurity       ErrorCategory = "modsecurity"
+	ErrFileNotFound      ErrorCategory = "file_not_found"
+	ErrPermissionDenied  ErrorCategory = "permission_denied"
+	ErrOther             ErrorCategory = "other"
+)
+
+// ErrorLogEntry is a structured nginx error.log / Apache error_log event
+type ErrorLogEntry struct {
+	Time         time.Time
+	Level        string // error, warn, crit, ... (Apache: without the module)
+	Module       string // Apache only, e.g. "proxy" in [proxy:error]
+	PID          int
+	TID          int
+	ConnectionID int64 // nginx *N connection number
+	ClientIP     string
+	Server       string
+	Request      string // e.g. "GET / HTTP/1.1"
+	Upstream     string
+	Host         string
+	Message      string // Without the trailing client/server/request context
+	Category     ErrorCategory
+}
+
+var (
+	// 2023/12/12 14:00:00 [error] 1234#5678: *99 message, client: 1.2.3.4, server: example.com, ...
+	nginxErrorRegex = regexp.MustCompile(`^(\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}) \[(\w+)\] (\d+)#(\d+): (?:\*(\d+) )?(.*)$`)
+
+	// [Tue Dec 12 14:00:00.123456 2023] [proxy:error] [pid 1234:tid 5678] [client 1.2.3.4:5678] message
+	// [Tue Dec 12 14:00:00 2023] [error] [client 1.2.3.4] message (Apache 2.2)
+	apacheErrorRegex = regexp.MustCompile(`^\[([^\]]+)\] \[(?:([\w-]+):)?(\w+)\](?: \[pid (\d+)(?::tid (\d+))?\])?(?: \[client ([^\]]+)\])? (.*)$`)
+
+	// Context nginx appends to messages
+	nginxContextRegex = regexp.MustCompile(`, (client|server|request|upstream|host|referrer): ("[^"]*"|[^,]*)`)
+
+	// Apache puts the request context in the message itself
+	apacheHostnameRegex = regexp.MustCompile(`\[hostname "([^"]*)"\]`)
+	apacheURIRegex      = regexp.MustCompile(`\[uri "([^"]*)"\]`)
+	apacheUpstreamRegex = regexp.MustCompile(`(?:connect to|remote server) (\S+:\d+)`)
+)
+
+// ParseErrorLine parses an nginx or Apache error log line (the format is
+// recognised per line).
+func ParseErrorLine(line string) (*ErrorLogEntry, error) {
+	if m := nginxErrorRegex.FindStringSubmatch(line); m != nil {
+		return parseNginxError(m), nil
+	}
+	if m := apacheErrorRegex.FindStringSubmatch(line); m != nil {
+		return parseApacheError(m), nil
+	}
+	return nil, fmt.Errorf("failed to parse error log line: %s", line)
+}
+
+func parseNginxError(m []string) *ErrorLogEntry {
+	e := &ErrorLogEntry{Level: m[2]}
+	e.Time, _ = time.ParseInLocation("2006/01/02 15:04:05", m[1], time.Local)
+	e.PID, _ = strconv.Atoi(m[3])
+	e.TID, _ = strconv.Atoi(m[4])
+	if m[5] != "" {
+		e.ConnectionID, _ = strconv.ParseInt(m[5], 10, 64)
+	}
+
+	msg := m[6]
+	if loc := nginxContextRegex.FindStringIndex(msg); loc != nil {
+		for _, c := range nginxContextRegex.FindAllStringSubmatch(msg[loc[0]:], -1) {
+			value := strings.Trim(c[2], `"`)
+			switch c[1] {
+			case "client":
+				e.ClientIP = value
+			case "server":
+				e.Server = value
+			case "request":
+				e.Request = value
+			case "upstream":
+				e.Upstream = value
+			case "host":
+				e.Host = value
+			}
+		}
+		msg = msg[:loc[0]]
+	}
+	e.Message = msg
+	e.Category = categorizeError(msg)
+	return e
+}
+
+func parseApacheError(m []string) *ErrorLogEntry {
+	e := &ErrorLogEntry{Module: m[2], Level: m[3], Message: m[7]}
+	e.Time, _ = time.ParseInLocation("Mon Jan _2 15:04:05 2006", m[1], time.Local)
+	e.PID, _ = strconv.Atoi(m[4])
+	e.TID, _ = strconv.Atoi(m[5])
+	e.ClientIP = stripPort(m[6])
+
+	if h := apacheHostnameRegex.FindStringSubmatch(e.Message); h != nil {
+		e.Host = h[1]
+	}
+	if u := apacheURIRegex.FindStringSubmatch(e.Message); u != nil {
+		e.Request = u[1]
+	}
+	if e.Module == "proxy" || e.Module == "proxy_http" {
+		if u := apacheUpstreamRegex.FindStringSubmatch(e.Message); u != nil {
+			e.Upstream = u[1]
+		}
+	}
+	e.Category = categorizeError(e.Message)
+	return e
+}
+
+// stripPort removes the port from "1.2.3.4:5678" or "[::1]:5678"
+func stripPort(addr string) string {
+	if strings.HasPrefix(addr, "[") {
+		if i := strings.Index(addr, "]"); i > 0 {
+			return addr[1:i]
+		}
+	}
+	if strings.Count(addr, ":") == 1 {
+		return addr[:strings.Index(addr, ":")]
+	}
+	return addr
+}
+
+// errorCategories are checked in order; the first match wins
+var errorCategories = []struct {
+	category ErrorCategory
+	patterns []string
+}{
+	{ErrModSecurity, []string{"ModSecurity"}},
+	{ErrLimitReq, []string{"limiting requests"}},
+	{ErrLimitConn, []string{"limiting connections"}},
+	{ErrSSLHandshake, []string{"SSL_do_handshake", "SSL handshake", "handshake failed", "AH02008", "AH02039"}},
+	{ErrUpstreamTimeout, []string{"upstream timed out", "timeout specified has expired", "AH01102"}},
+	{ErrClientTimeout, []string{"client timed out"}},
+	{ErrConnectionRefused, []string{"Connection refused"}},
+	{ErrUpstreamFailure, []string{"upstream prematurely closed", "no live upstreams", "reset by peer", "upstream sent", "AH00898", "AH01097"}},
+	{ErrFileNotFound, []string{"No such file or directory", "File does not exist", "AH00128"}},
+	{ErrPermissionDenied, []string{"Permission denied", "AH01630", "AH01797"}},
+}
+
+func categorizeError(msg string) ErrorCategory {
+	for _, c := range errorCategories {
+		for _, p := range c.patterns {
+			if strings.Contains(msg, p) {
+				return c.category
+			}
+		}
+	}
+	return ErrOther
+}
@@ -0,0 +1,64 @@
+package parser
+
+import (
+	"testing"
+)
+
+func TestParseErrorLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want ErrorLogEntry
+	}{
+		{
+			"nginx upstream timeout",
+			`2023/12/12 14:00:00 [error] 1234#5678: *99 upstream timed out (110: Connection timed out) while reading response header from upstream, client: 1.2.3.4, server: example.com, request: "GET /api HTTP/1.1", upstream: "http://10.0.0.1:8080/api", host: "example.com"`,
+			ErrorLogEntry{Level: "error", PID: 1234, TID: 5678, ConnectionID: 99, ClientIP: "1.2.3.4", Server: "example.com", Request: "GET /api HTTP/1.1", Upstream: "http://10.0.0.1:8080/api", Host: "example.com", Category: ErrUpstreamTimeout},
+		},
+		{
+			"nginx limit_req",
+			`2023/12/12 14:00:00 [warn] 10#10: *3 limiting requests, excess: 10.500 by zone "one", client: 5.6.7.8, server: _, request: "POST /login HTTP/1.1", host: "shop"`,
+			ErrorLogEntry{Level: "warn", PID: 10, TID: 10, ConnectionID: 3, ClientIP: "5.6.7.8", Server: "_", Request: "POST /login HTTP/1.1", Host: "shop", Category: ErrLimitReq},
+		},
+		{
+			"nginx connection refused",
+			`2023/12/12 14:00:00 [error] 10#10: *4 connect() failed (111: Connection refused) while connecting to upstream, client: 5.6.7.8, server: _, request: "GET / HTTP/1.1", upstream: "http://127.0.0.1:3000/", host: "shop"`,
+			ErrorLogEntry{Level: "error", PID: 10, TID: 10, ConnectionID: 4, ClientIP: "5.6.7.8", Server: "_", Request: "GET / HTTP/1.1", Upstream: "http://127.0.0.1:3000/", Host: "shop", Category: ErrConnectionRefused},
+		},
+		{
+			"Apache proxy refused",
+			`[Tue Dec 12 14:00:00.123456 2023] [proxy:error] [pid 1234:tid 5678] (111)Connection refused: AH00957: http: attempt to connect to 127.0.0.1:8080 (localhost) failed`,
+			ErrorLogEntry{Level: "error", Module: "proxy", PID: 1234, TID: 5678, Upstream: "127.0.0.1:8080", Category: ErrConnectionRefused},
+		},
+		{
+			"Apache ModSecurity",
+			`[Tue Dec 12 14:00:00.123456 2023] [security2:error] [pid 42] [client 9.9.9.9:51234] ModSecurity: Access denied with code 403 (phase 2). [hostname "shop.example.com"] [uri "/admin"]`,
+			ErrorLogEntry{Level: "error", Module: "security2", PID: 42, ClientIP: "9.9.9.9", Host: "shop.example.com", Request: "/admin", Category: ErrModSecurity},
+		},
+		{
+			"Apache 2.2 SSL",
+			`[Tue Dec 12 14:00:00 2023] [info] [client 9.9.9.9] SSL handshake failed: HTTP spoken on HTTPS port`,
+			ErrorLogEntry{Level: "info", ClientIP: "9.9.9.9", Category: ErrSSLHandshake},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseErrorLine(tt.line)
+			if err != nil {
+				t.Fatalf("ParseErrorLine() error = %v", err)
+			}
+			if got.Time.IsZero() {
+				t.Errorf("ParseErrorLine() time not parsed")
+			}
+			got.Time, got.Message = tt.want.Time, tt.want.Message
+			if *got != tt.want {
+				t.Errorf("ParseErrorLine() = %+v; want %+v", *got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseErrorLine("PHP Stack trace:"); err == nil {
+		t.Error("ParseErrorLine(continuation) error = nil; want error")
+	}
+}
@@ -0,0 +1,24 @@
+package parser
+
+import "regexp"
+
+var (
+	// Qualified Java/Scala/Kotlin class names: java.lang.NullPointerException,
+	// org.example.FooError, "Exception in thread ... java.io.IOException: ..."
+	javaException = regexp.MustCompile(`(?:^|[\s"(:])((?:[a-zA-Z_$][\w$]*\.)+[A-Z][\w$]*(?:Exception|Error|Throwable|Fault))\b`)
+
+	// Python tracebacks and similar end with "ValueError: message"
+	plainException = regexp.MustCompile(`(?m)^([A-Z]\w*(?:Exception|Error)):`)
+)
+
+// ExceptionClass returns the class of the first exception mentioned in a
+// (multi-line) application log event, or "" if there is none
+func ExceptionClass(event string) string {
+	if m := javaException.FindStringSubmatch(event); m != nil {
+		return m[1]
+	}
+	if m := plainException.FindStringSubmatch(event); m != nil {
+		return m[1]
+	}
+	return ""
+}
@@ -0,0 +1,38 @@
+package parser
+
+import "testing"
+
+func TestExceptionClass(t *testing.T) {
+	tests := []struct {
+		name  string
+		event string
+		want  string
+	}{
+		{
+			"Tomcat",
+			"12-Dec-2023 14:00:00.000 SEVERE [http-nio-8080-exec-1] org.apache.catalina.core.StandardWrapperValve.invoke Servlet.service() for servlet [app] threw exception\n" +
+				"java.lang.NullPointerException: Cannot invoke \"String.length()\"\n\tat com.example.Foo.bar(Foo.java:42)\n" +
+				"Caused by: java.io.IOException: broken pipe",
+			"java.lang.NullPointerException",
+		},
+		{
+			"Uncaught",
+			"Exception in thread \"main\" java.lang.IllegalStateException: boom\n\tat Main.main(Main.java:3)",
+			"java.lang.IllegalStateException",
+		},
+		{
+			"Python",
+			"Traceback (most recent call last):\n  File \"app.py\", line 3, in <module>\nValueError: bad value",
+			"ValueError",
+		},
+		{"No exception", "INFO Server startup in [1234] milliseconds", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExceptionClass(tt.event); got != tt.want {
+				t.Errorf("ExceptionClass() = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fieldSetters are the GenericLogEntry fields that values extracted by the
+// configurable parsers (grok, ...) can be mapped onto
+var fieldSetters = map[string]func(e *GenericLogEntry, v string){
+	"remote_ip":    func(e *GenericLogEntry, v string) { e.RemoteIP = v },
+	"remote_user":  func(e *GenericLogEntry, v string) { e.RemoteUser = v },
+	"method":       func(e *GenericLogEntry, v string) { e.Method = v },
+	"path":         func(e *GenericLogEntry, v string) { e.Path = v },
+	"protocol":     func(e *GenericLogEntry, v string) { e.Protocol = v },
+	"http_version": func(e *GenericLogEntry, v string) { e.Protocol = "HTTP/" + v },
+	"request_line": func(e *GenericLogEntry, v string) {
+		parts := strings.SplitN(v, " ", 3)
+		e.Method = parts[0]
+		if len(parts) > 1 {
+			e.Path = parts[1]
+		}
+		if len(parts) > 2 {
+			e.Protocol = parts[2]
+		}
+	},
+	"status":           func(e *GenericLogEntry, v string) { e.Status, _ = strconv.Atoi(v) },
+	"bytes_sent":       func(e *GenericLogEntry, v string) { e.BodyBytesSent, _ = strconv.Atoi(v) },
+	"referer":          func(e *GenericLogEntry, v string) { e.Referer = v },
+	"user_agent":       func(e *GenericLogEntry, v string) { e.UserAgent = v },
+	"latency":          func(e *GenericLogEntry, v string) { e.Latency = parseLatency(v) },
+	"latency_ms":       func(e *GenericLogEntry, v string) { e.Latency = parseScaled(v, 1e3) },
+	"latency_us":       func(e *GenericLogEntry, v string) { e.Latency = parseScaled(v, 1e6) },
+	"latency_ns":       func(e *GenericLogEntry, v string) { e.Latency = parseScaled(v, 1e9) },
+	"host":             func(e *GenericLogEntry, v string) { e.Host = v },
+	"request_id":       func(e *GenericLogEntry, v string) { e.RequestID = v },
+	"request_bytes":    func(e *GenericLogEntry, v string) { e.RequestBytes, _ = strconv.Atoi(v) },
+	"forwarded_for":    func(e *GenericLogEntry, v string) { e.ForwardedFor = splitForwardedFor(v) },
+	"upstream_addr":    func(e *GenericLogEntry, v string) { e.UpstreamAddr = lastUpstream(v) },
+	"upstream_latency": func(e *GenericLogEntry, v string) { e.UpstreamLatency = sumLatencies(v) },
+	"tls_protocol":     func(e *GenericLogEntry, v string) { e.TLSProtocol = v },
+	"tls_cipher":       func(e *GenericLogEntry, v string) { e.TLSCipher = v },
+}
+
+// fieldAliases are source names mapped without configuration: the field
+// names of the stock grok web server patterns
+var fieldAliases = map[string]string{
+	"clientip":    "remote_ip",
+	"auth":        "remote_user",
+	"timestamp":   "time",
+	"verb":        "method",
+	"request":     "path",
+	"httpversion": "http_version",
+	"response":    "status",
+	"bytes":       "bytes_sent",
+	"referrer":    "referer",
+	"agent":       "user_agent",
+}
+
+// FieldTargets returns the names values can be mapped onto: the
+// GenericLogEntry fields, "time", "extra" (keep in Extra) and "-" (drop).
+func FieldTargets() []string {
+	names := []string{"time", "extra", "-"}
+	for name := range fieldSetters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fieldMapper assigns extracted values to GenericLogEntry fields according
+// to a source -> target mapping. Sources that are neither mapped nor named
+// like a target (or a known alias) end up in Extra.
+type fieldMapper struct {
+	fields     map[string]string
+	timeLayout string
+}
+
+func newFieldMapper(fields map[string]string, timeLayout string) (*fieldMapper, error) {
+	for source, target := range fields {
+		if _, ok := fieldSetters[target]; !ok && target != "time" && target != "extra" && target != "-" {
+			return nil, fmt.Errorf("field %q: unknown target %q (known: %v)", source, target, FieldTargets())
+		}
+	}
+	return &fieldMapper{fields: fields, timeLayout: timeLayout}, nil
+}
+
+func (m *fieldMapper) target(source string) string {
+	if target, ok := m.fields[source]; ok {
+		return target
+	}
+	if _, ok := fieldSetters[source]; ok || source == "time" {
+		return source
+	}
+	if target, ok := fieldAliases[source]; ok {
+		return target
+	}
+	return "extra"
+}
+
+// set maps one extracted value onto entry
+func (m *fieldMapper) set(entry *GenericLogEntry, source, value string) {
+	target := m.target(source)
+	switch target {
+	case "-":
+		return
+	case "extra":
+		entry.SetExtra(source, value)
+		return
+	}
+
+	value = strings.Trim(value, `"`) // Quoted strings (grok QS)
+	if value == "-" || value == "" {
+		return // Placeholder for a missing value
+	}
+	if target == "time" {
+		entry.TimeLocal = parseTime(m.timeLayout, value)
+		return
+	}
+	fieldSetters[target](entry, value)
+}
+
+// parseLatency reads seconds, or a Go duration such as "12ms" or "1.5s"
+func parseLatency(value string) float64 {
+	if v, err := strconv.ParseFloat(value, 64); err == nil {
+		return v
+	}
+	d, _ := time.ParseDuration(value)
+	return d.Seconds()
+}
+
+func parseScaled(value string, perSecond float64) float64 {
+	v, _ := strconv.ParseFloat(value, 64)
+	return v / perSecond
+}
+
+// timeLayouts are tried in order when no layout is configured
+var timeLayouts = []string{
+	"02/Jan/2006:15:04:05 -0700", // HTTPDATE
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999 -0700",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+}
+
+// epochScale is the number of units per second of each epoch layout
+var epochScale = map[string]float64{"unix": 1, "unix_ms": 1e3, "unix_us": 1e6, "unix_ns": 1e9}
+
+// parseTime parses value with layout (a Go layout, or "unix", "unix_ms",
+// "unix_us", "unix_ns"), or with the common layouts if layout is empty.
+// Returns the zero time if the value cannot be parsed.
+func parseTime(layout, value string) time.Time {
+	if scale, ok := epochScale[layout]; ok {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return time.Time{}
+		}
+		return epochTime(f, scale)
+	}
+	if layout != "" {
+		t, _ := time.Parse(layout, value)
+		return t
+	}
+
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		// Guess the unit from the magnitude (seconds until year ~5000)
+		switch {
+		case f > 1e17:
+			return epochTime(f, 1e9)
+		case f > 1e14:
+			return epochTime(f, 1e6)
+		case f > 1e11:
+			return epochTime(f, 1e3)
+		}
+		return epochTime(f, 1)
+	}
+	for _, l := range timeLayouts {
+		if t, err := time.Parse(l, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func epochTime(value, perSecond float64) time.Time {
+	return time.UnixMicro(int64(value / perSecond * 1e6))
+}
@@ -0,0 +1,109 @@
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Firewall actions, normalized from the log prefix
+const (
+	FirewallAllow  = "allow"
+	FirewallBlock  = "block" // DROP, DENY, BLOCK
+	FirewallReject = "reject"
+	FirewallLimit  = "limit" // UFW rate limiting
+	FirewallAudit  = "audit" // UFW AUDIT, logged but not decided
+	FirewallLog    = "log"   // Prefix without a known action
+)
+
+// FirewallEntry is a packet logged by netfilter (iptables LOG, nftables
+// log, UFW)
+type FirewallEntry struct {
+	Time         time.Time // From the syslog header, zero if none
+	Prefix       string    // Log prefix, e.g. "UFW BLOCK"
+	Action       string    // FirewallBlock, ...
+	InInterface  string
+	OutInterface string
+	SrcIP        string
+	DstIP        string
+	Proto        string // TCP, UDP, ICMP, ICMPv6, ... (or a number)
+	SrcPort      int    // 0 without ports (ICMP)
+	DstPort      int
+	Length       int
+}
+
+var (
+	// kernel: [12345.678901] [UFW BLOCK] IN=eth0 OUT= MAC=... SRC=1.2.3.4 DST=10.0.0.1 LEN=60 ... PROTO=TCP SPT=40000 DPT=22 ...
+	// Groups: 1 prefix, 2 fields
+	firewallRegex = regexp.MustCompile(`^(?:\[\s*\d+\.\d+\]\s*)?(.*?)\s*(IN=\S* OUT=.*)$`)
+
+	firewallFieldRegex = regexp.MustCompile(`([A-Z]+)=(\S*)`)
+
+	firewallActionRegex = regexp.MustCompile(`(?i)(allow|accept|pass|block|drop|deny|reject|limit|audit)`)
+)
+
+// ParseFirewallLine recognizes netfilter packet log lines, from a kernel
+// log (kern.log, ufw.log, dmesg) with or without syslog header. Returns nil
+// for other lines.
+func ParseFirewallLine(line string) *FirewallEntry {
+	msg := line
+	h, ok := ParseSyslogHeader(line)
+	if ok {
+		msg = h.Message
+	}
+	m := firewallRegex.FindStringSubmatch(msg)
+	if m == nil {
+		return nil
+	}
+
+	e := &FirewallEntry{Time: h.Time}
+	e.Prefix = strings.TrimSpace(strings.Trim(strings.TrimSpace(m[1]), "[]:"))
+	e.Action = firewallAction(e.Prefix)
+	for _, f := range firewallFieldRegex.FindAllStringSubmatch(m[2], -1) {
+		switch f[1] {
+		case "IN":
+			e.InInterface = f[2]
+		case "OUT":
+			e.OutInterface = f[2]
+		case "SRC":
+			e.SrcIP = f[2]
+		case "DST":
+			e.DstIP = f[2]
+		case "PROTO":
+			e.Proto = f[2]
+		case "SPT":
+			e.SrcPort, _ = strconv.Atoi(f[2])
+		case "DPT":
+			e.DstPort, _ = strconv.Atoi(f[2])
+		case "LEN":
+			if e.Length == 0 { // IP length comes first, then UDP's
+				e.Length, _ = strconv.Atoi(f[2])
+			}
+		}
+	}
+	if e.SrcIP == "" {
+		return nil
+	}
+	return e
+}
+
+// firewallAction finds the action in a log prefix ("[UFW BLOCK]",
+// "IPTABLES-DROP: ", "nft drop in"). "UFW LIMIT BLOCK" is a limit.
+func firewallAction(prefix string) string {
+	m := firewallActionRegex.FindStringSubmatch(prefix)
+	if m == nil {
+		return FirewallLog
+	}
+	switch strings.ToLower(m[1]) {
+	case "allow", "accept", "pass":
+		return FirewallAllow
+	case "reject":
+		return FirewallReject
+	case "limit":
+		return FirewallLimit
+	case "audit":
+		return FirewallAudit
+	}
+	return FirewallBlock
+}
@@ -0,0 +1,61 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFirewallLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want *FirewallEntry
+	}{
+		{
+			"UFW block",
+			"Dec 12 14:00:00 web1 kernel: [123456.789012] [UFW BLOCK] IN=eth0 OUT= MAC=00:11:22:33:44:55:66:77:88:99:aa:bb:08:00 SRC=203.0.113.9 DST=10.0.0.5 LEN=60 TOS=0x00 PREC=0x00 TTL=50 ID=54321 DF PROTO=TCP SPT=40000 DPT=22 WINDOW=29200 RES=0x00 SYN URGP=0",
+			&FirewallEntry{Prefix: "UFW BLOCK", Action: FirewallBlock, InInterface: "eth0", SrcIP: "203.0.113.9", DstIP: "10.0.0.5", Proto: "TCP", SrcPort: 40000, DstPort: 22, Length: 60},
+		},
+		{
+			"UFW limit",
+			"Dec 12 14:00:00 web1 kernel: [UFW LIMIT BLOCK] IN=eth0 OUT= SRC=203.0.113.9 DST=10.0.0.5 LEN=60 PROTO=TCP SPT=40001 DPT=22",
+			&FirewallEntry{Prefix: "UFW LIMIT BLOCK", Action: FirewallLimit, InInterface: "eth0", SrcIP: "203.0.113.9", DstIP: "10.0.0.5", Proto: "TCP", SrcPort: 40001, DstPort: 22, Length: 60},
+		},
+		{
+			"iptables prefix, UDP",
+			"Dec 12 14:00:00 web1 kernel: IPTABLES-INPUT-DROP: IN=ens3 OUT= SRC=198.51.100.7 DST=10.0.0.5 LEN=78 TOS=0x00 TTL=113 ID=1 PROTO=UDP SPT=53000 DPT=161 LEN=58",
+			&FirewallEntry{Prefix: "IPTABLES-INPUT-DROP", Action: FirewallBlock, InInterface: "ens3", SrcIP: "198.51.100.7", DstIP: "10.0.0.5", Proto: "UDP", SrcPort: 53000, DstPort: 161, Length: 78},
+		},
+		{
+			"nftables accept, IPv6 ICMP, dmesg",
+			"[ 9876.543210] nft accept: IN=eth0 OUT= SRC=2001:db8::1 DST=2001:db8::2 LEN=104 TC=0 HOPLIMIT=64 FLOWLBL=0 PROTO=ICMPv6 TYPE=128 CODE=0",
+			&FirewallEntry{Prefix: "nft accept", Action: FirewallAllow, InInterface: "eth0", SrcIP: "2001:db8::1", DstIP: "2001:db8::2", Proto: "ICMPv6", Length: 104},
+		},
+		{
+			"Forwarded, no action",
+			"Dec 12 14:00:00 gw kernel: FWD IN=eth1 OUT=eth0 SRC=10.0.0.7 DST=192.0.2.1 LEN=40 PROTO=TCP SPT=5000 DPT=443",
+			&FirewallEntry{Prefix: "FWD", Action: FirewallLog, InInterface: "eth1", OutInterface: "eth0", SrcIP: "10.0.0.7", DstIP: "192.0.2.1", Proto: "TCP", SrcPort: 5000, DstPort: 443, Length: 40},
+		},
+		{"Other kernel line", "Dec 12 14:00:00 web1 kernel: [1.0] eth0: link up", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseFirewallLine(tt.line)
+			if got == nil || tt.want == nil {
+				if got != tt.want {
+					t.Errorf("ParseFirewallLine() = %+v; want %+v", got, tt.want)
+				}
+				return
+			}
+			if hasHeader := strings.HasPrefix(tt.line, "Dec "); got.Time.IsZero() == hasHeader {
+				t.Errorf("Time = %v", got.Time)
+			}
+			got.Time = time.Time{}
+			if *got != *tt.want {
+				t.Errorf("ParseFirewallLine() = %+v; want %+v", *got, *tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GCPLoadBalancerParser reads Google Cloud HTTP(S) load balancer request
+// logs as exported from Cloud Logging (one LogEntry JSON object per line)
+type GCPLoadBalancerParser struct{}
+
+// GCPLogEntry holds the LogEntry fields used for load balancer requests
+type GCPLogEntry struct {
+	Timestamp   string `json:"timestamp"`
+	Trace       string `json:"trace"`
+	HTTPRequest *struct {
+		RequestMethod string      `json:"requestMethod"`
+		RequestURL    string      `json:"requestUrl"`
+		RequestSize   json.Number `json:"requestSize"` // int64 values are JSON strings
+		Status        int         `json:"status"`
+		ResponseSize  json.Number `json:"responseSize"`
+		UserAgent     string      `json:"userAgent"`
+		RemoteIP      string      `json:"remoteIp"`
+		ServerIP      string      `json:"serverIp"`
+		Referer       string      `json:"referer"`
+		Latency       string      `json:"latency"` // e.g. "0.012345s"
+		Protocol      string      `json:"protocol"`
+		CacheHit      bool        `json:"cacheHit"`
+	} `json:"httpRequest"`
+	JSONPayload struct {
+		StatusDetails string `json:"statusDetails"` // e.g. "response_sent_by_backend"
+		CacheID       string `json:"cacheId"`       // Cloud CDN edge, e.g. "SFO-fc5d1a6e"
+	} `json:"jsonPayload"`
+	Resource struct {
+		Labels map[string]string `json:"labels"`
+	} `json:"resource"`
+}
+
+// gcpResourceLabels are the resource labels kept in Extra
+var gcpResourceLabels = map[string]string{
+	"backend_service_name": "backend_service",
+	"forwarding_rule_name": "forwarding_rule",
+	"url_map_name":         "url_map",
+	"target_proxy_name":    "target_proxy",
+	"region":               "region",
+}
+
+func (p *GCPLoadBalancerParser) Parse(line string) (*GenericLogEntry, error) {
+	var doc GCPLogEntry
+	if err := json.Unmarshal([]byte(line), &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse gcp json: %v", err)
+	}
+	req := doc.HTTPRequest
+	if req == nil {
+		return nil, fmt.Errorf("not a gcp load balancer log line: %s", line)
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, doc.Timestamp)
+	if err != nil {
+		t = time.Now()
+	}
+	requestSize, _ := strconv.Atoi(req.RequestSize.String())
+	responseSize, _ := strconv.Atoi(req.ResponseSize.String())
+
+	entry := &GenericLogEntry{
+		Service:       "gcp_lb",
+		RemoteIP:      req.RemoteIP,
+		RemoteUser:    "-",
+		TimeLocal:     t,
+		Method:        req.RequestMethod,
+		Path:          req.RequestURL,
+		Protocol:      req.Protocol,
+		Status:        req.Status,
+		BodyBytesSent: responseSize,
+		Referer:       req.Referer,
+		UserAgent:     req.UserAgent,
+		Latency:       parseLatency(req.Latency),
+		RequestBytes:  requestSize,
+		UpstreamAddr:  req.ServerIP,
+	}
+	if u, err := url.Parse(req.RequestURL); err == nil && u.Host != "" {
+		entry.Path = u.RequestURI()
+		entry.Host = u.Hostname()
+		entry.SetExtra("scheme", u.Scheme)
+	}
+	// "projects/my-project/traces/06796866738c859f2f19b7cfb3214824"
+	if i := strings.LastIndex(doc.Trace, "/"); i >= 0 {
+		entry.RequestID = doc.Trace[i+1:]
+	}
+
+	if doc.JSONPayload.StatusDetails != "" {
+		entry.SetExtra("status_details", doc.JSONPayload.StatusDetails)
+	}
+	if id := doc.JSONPayload.CacheID; id != "" {
+		location, _, _ := strings.Cut(id, "-")
+		entry.SetExtra("edge_location", location)
+		entry.SetExtra("cache_id", id)
+	}
+	if req.CacheHit {
+		entry.SetExtra("cache_hit", "true")
+	}
+	for label, key := range gcpResourceLabels {
+		if v := doc.Resource.Labels[label]; v != "" {
+			entry.SetExtra(key, v)
+		}
+	}
+	return entry, nil
+}
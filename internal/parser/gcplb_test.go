@@ -0,0 +1,40 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGCPLoadBalancerParser(t *testing.T) {
+	line := `{"httpRequest":{"requestMethod":"GET","requestUrl":"https://shop.example.com/cart?id=7","requestSize":"412","status":502,"responseSize":"1180","userAgent":"curl/8.0","remoteIp":"203.0.113.7","serverIp":"10.128.0.12","latency":"0.250s","protocol":"HTTP/1.1"},` +
+		`"jsonPayload":{"@type":"type.googleapis.com/google.cloud.loadbalancing.type.LoadBalancerLogEntry","statusDetails":"failed_to_connect_to_backend","cacheId":"SFO-fc5d1a6e"},` +
+		`"resource":{"type":"http_load_balancer","labels":{"backend_service_name":"shop-backend","forwarding_rule_name":"shop-https","url_map_name":"shop","target_proxy_name":"shop-proxy","zone":"global","project_id":"p"}},` +
+		`"timestamp":"2023-12-12T14:00:00.123456Z","severity":"WARNING","trace":"projects/p/traces/06796866738c859f2f19b7cfb3214824"}`
+
+	got, err := (&GCPLoadBalancerParser{}).Parse(line)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if want := time.Date(2023, 12, 12, 14, 0, 0, 123456000, time.UTC); !got.TimeLocal.Equal(want) {
+		t.Errorf("TimeLocal = %v; want %v", got.TimeLocal, want)
+	}
+	extra := got.Extra
+	got.TimeLocal, got.Service, got.Extra = time.Time{}, "", nil
+	want := GenericLogEntry{RemoteIP: "203.0.113.7", RemoteUser: "-", Method: "GET", Path: "/cart?id=7", Protocol: "HTTP/1.1", Status: 502, BodyBytesSent: 1180, UserAgent: "curl/8.0",
+		Latency: 0.25, Host: "shop.example.com", RequestID: "06796866738c859f2f19b7cfb3214824", RequestBytes: 412, UpstreamAddr: "10.128.0.12"}
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("Parse() = %+v; want %+v", *got, want)
+	}
+	wantExtra := map[string]string{
+		"scheme": "https", "status_details": "failed_to_connect_to_backend", "edge_location": "SFO", "cache_id": "SFO-fc5d1a6e",
+		"backend_service": "shop-backend", "forwarding_rule": "shop-https", "url_map": "shop", "target_proxy": "shop-proxy",
+	}
+	if !reflect.DeepEqual(map[string]string(extra), wantExtra) {
+		t.Errorf("Parse() extra = %v; want %v", extra, wantExtra)
+	}
+
+	if _, err := (&GCPLoadBalancerParser{}).Parse(`{"severity":"INFO","textPayload":"started"}`); err == nil {
+		t.Error("Parse(other log entry) error = nil; want error")
+	}
+}
@@ -0,0 +1,175 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// GrokConfig defines a pattern-based parser, typically from the config file
+type GrokConfig struct {
+	Name string
+
+	// Patterns are tried in order, anchored at the start of the line. Each
+	// may use grok syntax (%{IPORHOST:client}, %{NUMBER:bytes:int}) and/or
+	// plain regexes with named captures ((?P<client>\S+)).
+	Patterns []string
+
+	// PatternDefinitions adds to (or overrides) the standard library
+	PatternDefinitions map[string]string
+
+	// Fields maps captured names onto GenericLogEntry (see FieldTargets).
+	// Captures named like a target are mapped implicitly; others go to Extra.
+	Fields map[string]string
+
+	// TimeLayout is a Go time layout or "unix" ("unix_ms", "unix_us",
+	// "unix_ns") for the "time" field. Empty tries the common layouts
+	// (HTTPDATE, RFC 3339, epoch, ...).
+	TimeLayout string
+}
+
+// GrokParser parses lines with the first matching grok pattern
+type GrokParser struct {
+	Name     string
+	patterns []grokPattern
+	mapper   *fieldMapper
+
+	// Observe, if set, is called for every pattern tried on a line, in
+	// order, with the pattern as configured and whether it matched
+	Observe func(pattern string, matched bool)
+}
+
+// ErrNoMatch is returned by GrokParser.Parse for lines no pattern matches
+var ErrNoMatch = errors.New("no pattern matched")
+
+type grokPattern struct {
+	source string // As configured
+	re     *regexp.Regexp
+	fields []string // Source field name per capture group, "" if unnamed
+}
+
+// grokRef matches %{SYNTAX}, %{SYNTAX:field} and %{SYNTAX:field:type}
+var grokRef = regexp.MustCompile(`%\{(\w+)(?::([^:}]+))?(?::\w+)?\}`)
+
+// maxGrokDepth bounds pattern nesting (and catches reference cycles)
+const maxGrokDepth = 20
+
+// NewGrokParser compiles every pattern of cfg once
+func NewGrokParser(cfg GrokConfig) (*GrokParser, error) {
+	if len(cfg.Patterns) == 0 {
+		return nil, fmt.Errorf("no patterns defined")
+	}
+	mapper, err := newFieldMapper(cfg.Fields, cfg.TimeLayout)
+	if err != nil {
+		return nil, err
+	}
+
+	library := make(map[string]string, len(grokPatterns)+len(cfg.PatternDefinitions))
+	for name, pat := range grokPatterns {
+		library[name] = pat
+	}
+	for name, pat := range cfg.PatternDefinitions {
+		library[name] = pat
+	}
+
+	p := &GrokParser{Name: cfg.Name, mapper: mapper}
+	for i, pattern := range cfg.Patterns {
+		gp, err := compileGrok(pattern, library)
+		if err != nil {
+			return nil, fmt.Errorf("patterns[%d]: %v", i, err)
+		}
+		gp.source = pattern
+		p.patterns = append(p.patterns, gp)
+	}
+	return p, nil
+}
+
+func compileGrok(pattern string, library map[string]string) (grokPattern, error) {
+	var fields []string // Field name per generated group
+	expr, err := expandGrok(pattern, library, &fields, 0)
+	if err != nil {
+		return grokPattern{}, err
+	}
+	re, err := regexp.Compile("^" + expr)
+	if err != nil {
+		return grokPattern{}, fmt.Errorf("invalid regular expression: %v", err)
+	}
+
+	gp := grokPattern{re: re, fields: make([]string, re.NumSubexp()+1)}
+	for i, name := range re.SubexpNames() {
+		if n, ok := strings.CutPrefix(name, "grok__"); ok {
+			idx, _ := strconv.Atoi(n)
+			gp.fields[i] = fields[idx]
+		} else {
+			gp.fields[i] = name
+		}
+	}
+	return gp, nil
+}
+
+// expandGrok replaces %{...} references recursively. Named references
+// become capture groups with generated names, as field names may contain
+// characters Go does not allow in group names.
+func expandGrok(pattern string, library map[string]string, fields *[]string, depth int) (string, error) {
+	if depth > maxGrokDepth {
+		return "", fmt.Errorf("patterns nested too deeply (cycle?)")
+	}
+
+	var expandErr error
+	expanded := grokRef.ReplaceAllStringFunc(pattern, func(ref string) string {
+		m := grokRef.FindStringSubmatch(ref)
+		def, ok := library[m[1]]
+		if !ok {
+			if expandErr == nil {
+				expandErr = fmt.Errorf("unknown pattern %%{%s}", m[1])
+			}
+			return ""
+		}
+		inner, err := expandGrok(def, library, fields, depth+1)
+		if err != nil {
+			if expandErr == nil {
+				expandErr = err
+			}
+			return ""
+		}
+		if m[2] == "" {
+			return "(?:" + inner + ")"
+		}
+		*fields = append(*fields, m[2])
+		return fmt.Sprintf("(?P<grok__%d>%s)", len(*fields)-1, inner)
+	})
+	return expanded, expandErr
+}
+
+// Parse implements LogParser
+func (p *GrokParser) Parse(line string) (*GenericLogEntry, error) {
+	for _, gp := range p.patterns {
+		loc := gp.re.FindStringSubmatchIndex(line)
+		if p.Observe != nil {
+			p.Observe(gp.source, loc != nil)
+		}
+		if loc == nil {
+			continue
+		}
+
+		entry := &GenericLogEntry{Service: p.Name}
+		for i, field := range gp.fields {
+			if field == "" || loc[2*i] < 0 {
+				continue // Unnamed, or an optional group that did not take part
+			}
+			p.mapper.set(entry, field, line[loc[2*i]:loc[2*i+1]])
+		}
+		return entry, nil
+	}
+	return nil, fmt.Errorf("%s: %w: %s", p.Name, ErrNoMatch, line)
+}
+
+// WithObserve returns a copy of p sharing its compiled patterns, with
+// Observe set, so each input can count matches under its own service
+func (p *GrokParser) WithObserve(observe func(pattern string, matched bool)) *GrokParser {
+	c := *p
+	c.Observe = observe
+	return &c
+}
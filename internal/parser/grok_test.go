@@ -0,0 +1,112 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGrokParser(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   GrokConfig
+		line  string
+		want  GenericLogEntry
+		extra map[string]string
+		time  time.Time
+	}{
+		{
+			"Combined log",
+			GrokConfig{Patterns: []string{"%{COMBINEDAPACHELOG}"}},
+			`10.0.0.1 - bob [12/Dec/2023:14:00:00 +0000] "GET /index.html?a=1 HTTP/1.1" 200 512 "-" "curl/8.0"`,
+			GenericLogEntry{RemoteIP: "10.0.0.1", RemoteUser: "bob", Method: "GET", Path: "/index.html?a=1", Protocol: "HTTP/1.1", Status: 200, BodyBytesSent: 512, UserAgent: "curl/8.0"},
+			map[string]string{"ident": "-"},
+			time.Date(2023, 12, 12, 14, 0, 0, 0, time.UTC),
+		},
+		{
+			"Custom definition and mapping",
+			GrokConfig{
+				Patterns:           []string{`%{IPORHOST:client} %{TIMESTAMP_ISO8601:time} %{WORD:method} %{URIPATHPARAM:path} %{NUMBER:status} %{NUMBER:took:int}ms tenant=%{TENANT:tenant}`},
+				PatternDefinitions: map[string]string{"TENANT": `[a-z]+`},
+				Fields:             map[string]string{"client": "remote_ip", "took": "latency_ms"},
+			},
+			`10.0.0.2 2023-12-12T14:00:00Z POST /api/v1?x=y 201 250ms tenant=acme`,
+			GenericLogEntry{RemoteIP: "10.0.0.2", Method: "POST", Path: "/api/v1?x=y", Status: 201, Latency: 0.25},
+			map[string]string{"tenant": "acme"},
+			time.Date(2023, 12, 12, 14, 0, 0, 0, time.UTC),
+		},
+		{
+			"Falls back to named regex",
+			GrokConfig{Patterns: []string{"%{IP:remote_ip} never", `(?P<remote_ip>\S+) - (?P<status>\d{3}) (?P<path>\S+)`}},
+			`10.0.0.3 - 404 /missing`,
+			GenericLogEntry{RemoteIP: "10.0.0.3", Status: 404, Path: "/missing"},
+			nil,
+			time.Time{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewGrokParser(tt.cfg)
+			if err != nil {
+				t.Fatalf("NewGrokParser() error = %v", err)
+			}
+			got, err := p.Parse(tt.line)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !got.TimeLocal.Equal(tt.time) {
+				t.Errorf("TimeLocal = %v; want %v", got.TimeLocal, tt.time)
+			}
+			extra := got.Extra
+			got.TimeLocal, got.Service, got.Extra = time.Time{}, "", nil
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Parse() = %+v; want %+v", *got, tt.want)
+			}
+			if len(extra) != len(tt.extra) {
+				t.Errorf("Parse() extra = %v; want %v", extra, tt.extra)
+			}
+			for k, v := range tt.extra {
+				if extra[k] != v {
+					t.Errorf("Extra[%q] = %q; want %q", k, extra[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestGrokParserErrors(t *testing.T) {
+	if _, err := NewGrokParser(GrokConfig{Patterns: []string{"%{NOPE:x}"}}); err == nil {
+		t.Error("unknown pattern: error = nil; want error")
+	}
+	if _, err := NewGrokParser(GrokConfig{Patterns: []string{"%{A}"}, PatternDefinitions: map[string]string{"A": "%{A}"}}); err == nil {
+		t.Error("recursive pattern: error = nil; want error")
+	}
+	if _, err := NewGrokParser(GrokConfig{Patterns: []string{"%{WORD:w}"}, Fields: map[string]string{"w": "nope"}}); err == nil {
+		t.Error("unknown field target: error = nil; want error")
+	}
+
+	var seen []string
+	p, _ := NewGrokParser(GrokConfig{Patterns: []string{"%{WORD:method} never", "%{INT:status}"}})
+	observed := p.WithObserve(func(pattern string, matched bool) {
+		seen = append(seen, fmt.Sprintf("%s %v", pattern, matched))
+	})
+	if _, err := observed.Parse("not a number"); !errors.Is(err, ErrNoMatch) {
+		t.Errorf("Parse() error = %v; want ErrNoMatch", err)
+	}
+	if _, err := observed.Parse("200"); err != nil {
+		t.Errorf("Parse() error = %v", err)
+	}
+	want := []string{
+		"%{WORD:method} never false", "%{INT:status} false", // not a number
+		"%{WORD:method} never false", "%{INT:status} true", // 200
+	}
+	if !reflect.DeepEqual(seen, want) {
+		t.Errorf("Observe called with %q; want %q", seen, want)
+	}
+	if p.Observe != nil {
+		t.Error("WithObserve modified the original parser")
+	}
+}
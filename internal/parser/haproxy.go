@@ -4,50 +4,128 @@ import (
 	"fmt"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type HAProxyParser struct{}
 
-// HAProxy HTTP log format (default)
-// Feb  6 12:14:14 localhost haproxy[14389]: 10.0.1.2:33313 [06/Feb/2009:12:14:14.655] frontend backend/srv1 10/0/30/69/109 200 2750 - - ---- 1/1/1/1/0 0/0 "GET /index.html HTTP/1.1"
+// HAProxy HTTP log format (option httplog), optionally behind a syslog prefix
+// Feb  6 12:14:14 localhost haproxy[14389]: 10.0.1.2:33313 [06/Feb/2009:12:14:14.655] frontend backend/srv1 10/0/30/69/109 200 2750 - - ---- 1/1/1/1/0 0/0 {host} {} "GET /index.html HTTP/1.1"
 // Regex groups:
-// 1: ClientIP
-// 2: Timestamp [06/Feb/2009:12:14:14.655]
-// 3: StatusCode
-// 4: BytesRead (Response size)
-// 5: Method
-// 6: Path
-// 7: Protocol
-var haproxyRegex = regexp.MustCompile(`]: (\S+):\d+ \[([^\]]+)\] \S+ \S+ \S+ (\d+) (\d+) \S+ \S+ \S+ \S+ \S+ "(\S+) (\S+) (\S+)"`)
+// 1-2: Client IP and port
+// 3: Accept date [06/Feb/2009:12:14:14.655]
+// 4: Frontend ("~" suffix for SSL)
+// 5-6: Backend / Server
+// 7-11: Timers TR/Tw/Tc/Tr/Ta (ms, -1 if not reached; Tq/Tw/Tc/Tr/Tt before 1.8)
+// 12: StatusCode
+// 13: Bytes read (response size)
+// 14-15: Captured request / response cookie
+// 16: Termination state
+// 17-21: Connections actconn/feconn/beconn/srv_conn/retries
+// 22-23: Queues srv_queue/backend_queue
+// 24-25: Captured request / response headers (optional)
+// 26: Request line
+var haproxyRegex = regexp.MustCompile(`(?:^|\s)(\S+):(\d+) \[([^\]]+)\] (\S+) ([^/\s]+)/(\S+) (-?\d+)/(-?\d+)/(-?\d+)/(-?\d+)/\+?(-?\d+) (\d+) \+?(\d+) (\S+) (\S+) (\S{4}) (\d+)/(\d+)/(\d+)/(\d+)/\+?(\d+) (\d+)/(\d+)(?: \{([^}]*)\})?(?: \{([^}]*)\})? "([^"]*)"`)
+
+// HAProxy TCP log format (option tcplog)
+// 10.0.1.2:33313 [06/Feb/2009:12:12:51.443] fnt bck/srv1 0/0/5007 212 -- 0/0/0/0/3 0/0
+// Groups: 1-2 client, 3 date, 4 frontend, 5-6 backend/server, 7-9 timers Tw/Tc/Tt,
+// 10 bytes read, 11 termination state, 12-16 connections, 17-18 queues
+var haproxyTCPRegex = regexp.MustCompile(`(?:^|\s)(\S+):(\d+) \[([^\]]+)\] (\S+) ([^/\s]+)/(\S+) (-?\d+)/(-?\d+)/\+?(-?\d+) \+?(\d+) (\S{2}) (\d+)/(\d+)/(\d+)/(\d+)/\+?(\d+) (\d+)/(\d+)\s*$`)
+
+// haproxyTimers are the Extra keys of the HTTP (TR/Tw/Tc/Tr/Ta) and TCP
+// (Tw/Tc/Tt) timers, in milliseconds
+var (
+	haproxyTimers    = []string{"time_request_ms", "time_queue_ms", "time_connect_ms", "time_response_ms", "time_total_ms"}
+	haproxyTCPTimers = []string{"time_queue_ms", "time_connect_ms", "time_total_ms"}
+)
+
+// haproxyCounters are the Extra keys of the connection and queue counts
+var haproxyCounters = []string{"actconn", "feconn", "beconn", "srv_conn", "retries", "srv_queue", "backend_queue"}
 
 func (p *HAProxyParser) Parse(line string) (*GenericLogEntry, error) {
-	matches := haproxyRegex.FindStringSubmatch(line)
-	if matches == nil {
-		return nil, fmt.Errorf("failed to parse haproxy line: %s", line)
+	if m := haproxyRegex.FindStringSubmatch(line); m != nil {
+		entry := haproxyEntry(m[1], m[3], m[4], m[5], m[6], m[12], m[13], m[16], m[17:24])
+		entry.SetExtra("mode", "http")
+		setHAProxyTimers(entry, m[7:12], haproxyTimers)
+
+		// "%{+Q}r": "GET /index.html HTTP/1.1", or "<BADREQ>"
+		parts := strings.SplitN(m[26], " ", 3)
+		entry.Method = parts[0]
+		if len(parts) > 1 {
+			entry.Path = parts[1]
+		}
+		if len(parts) > 2 {
+			entry.Protocol = parts[2]
+		}
+
+		for i, key := range []string{"captured_request_cookie", "captured_response_cookie"} {
+			if v := dash(m[14+i]); v != "" {
+				entry.SetExtra(key, v)
+			}
+		}
+		for i, key := range []string{"captured_request_headers", "captured_response_headers"} {
+			if m[24+i] != "" {
+				entry.SetExtra(key, m[24+i])
+			}
+		}
+		return entry, nil
+	}
+
+	if m := haproxyTCPRegex.FindStringSubmatch(line); m != nil {
+		entry := haproxyEntry(m[1], m[3], m[4], m[5], m[6], "", m[10], m[11], m[12:19])
+		entry.SetExtra("mode", "tcp")
+		entry.Protocol = "TCP"
+		setHAProxyTimers(entry, m[7:10], haproxyTCPTimers)
+		return entry, nil
 	}
+	return nil, fmt.Errorf("failed to parse haproxy line: %s", line)
+}
 
-	// Time parsing: 06/Feb/2009:12:14:14.655
+// haproxyEntry builds the fields common to the HTTP and TCP formats
+func haproxyEntry(client, date, frontend, backend, server, status, bytesRead, termination string, counters []string) *GenericLogEntry {
+	// Time parsing: 06/Feb/2009:12:14:14.655, in the host's time zone
 	layout := "02/Jan/2006:15:04:05.000"
-	t, err := time.Parse(layout, matches[2])
+	t, err := time.ParseInLocation(layout, date, time.Local)
 	if err != nil {
 		t = time.Now()
 	}
 
-	status, _ := strconv.Atoi(matches[3])
-	bytesSent, _ := strconv.Atoi(matches[4])
+	code, _ := strconv.Atoi(status)
+	bytesSent, _ := strconv.Atoi(bytesRead)
 
-	return &GenericLogEntry{
+	entry := &GenericLogEntry{
 		Service:       "haproxy",
-		RemoteIP:      matches[1],
-		RemoteUser:    "-", 
+		RemoteIP:      client,
+		RemoteUser:    "-",
 		TimeLocal:     t,
-		Method:        matches[5],
-		Path:          matches[6],
-		Protocol:      matches[7],
-		Status:        status,
+		Status:        code,
 		BodyBytesSent: bytesSent,
-		Referer:       "",
-		UserAgent:     "",
-	}, nil
+	}
+	entry.SetExtra("frontend", frontend)
+	entry.SetExtra("backend", backend)
+	entry.SetExtra("server", server)
+	entry.SetExtra("termination_state", termination)
+	for i, key := range haproxyCounters {
+		entry.SetExtra(key, counters[i])
+	}
+	return entry
+}
+
+// setHAProxyTimers records the timers (-1 when the phase was not reached)
+// and derives the latencies from the total time (last timer) and, for
+// HTTP, the server's response time (Tr)
+func setHAProxyTimers(entry *GenericLogEntry, values, keys []string) {
+	for i, key := range keys {
+		entry.SetExtra(key, values[i])
+	}
+	if total, err := strconv.Atoi(values[len(values)-1]); err == nil && total >= 0 {
+		entry.Latency = float64(total) / 1000
+	}
+	if len(values) == 5 {
+		if tr, err := strconv.Atoi(values[3]); err == nil && tr >= 0 {
+			entry.UpstreamLatency = float64(tr) / 1000
+		}
+	}
 }
@@ -0,0 +1,87 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHAProxyParser(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		want  GenericLogEntry
+		extra map[string]string
+		time  time.Time
+	}{
+		{
+			"HTTP with syslog prefix and captures",
+			`Feb  6 12:14:14 localhost haproxy[14389]: 10.0.1.2:33313 [06/Feb/2009:12:14:14.655] http-in~ static/srv1 10/0/30/69/109 200 2750 - - ---- 1/1/1/1/0 0/0 {example.com} {} "GET /index.html HTTP/1.1"`,
+			GenericLogEntry{RemoteIP: "10.0.1.2", RemoteUser: "-", Method: "GET", Path: "/index.html", Protocol: "HTTP/1.1", Status: 200, BodyBytesSent: 2750, Latency: 0.109, UpstreamLatency: 0.069},
+			map[string]string{
+				"mode": "http", "frontend": "http-in~", "backend": "static", "server": "srv1", "termination_state": "----",
+				"time_request_ms": "10", "time_queue_ms": "0", "time_connect_ms": "30", "time_response_ms": "69", "time_total_ms": "109",
+				"actconn": "1", "feconn": "1", "beconn": "1", "srv_conn": "1", "retries": "0", "srv_queue": "0", "backend_queue": "0",
+				"captured_request_headers": "example.com",
+			},
+			time.Date(2009, 2, 6, 12, 14, 14, 655000000, time.Local),
+		},
+		{
+			"HTTP server timeout",
+			`10.0.1.3:40000 [06/Feb/2009:12:14:14.655] fe be/srv2 0/0/1/-1/30001 504 194 - - sH-- 3/3/2/1/0 0/0 "POST /slow HTTP/1.1"`,
+			GenericLogEntry{RemoteIP: "10.0.1.3", RemoteUser: "-", Method: "POST", Path: "/slow", Protocol: "HTTP/1.1", Status: 504, BodyBytesSent: 194, Latency: 30.001},
+			map[string]string{
+				"mode": "http", "frontend": "fe", "backend": "be", "server": "srv2", "termination_state": "sH--",
+				"time_request_ms": "0", "time_queue_ms": "0", "time_connect_ms": "1", "time_response_ms": "-1", "time_total_ms": "30001",
+				"actconn": "3", "feconn": "3", "beconn": "2", "srv_conn": "1", "retries": "0", "srv_queue": "0", "backend_queue": "0",
+			},
+			time.Date(2009, 2, 6, 12, 14, 14, 655000000, time.Local),
+		},
+		{
+			"TCP",
+			`10.0.1.4:50000 [06/Feb/2009:12:12:51.443] fnt bck/srv1 0/0/5007 212 cD 0/0/0/0/3 0/0`,
+			GenericLogEntry{RemoteIP: "10.0.1.4", RemoteUser: "-", Protocol: "TCP", BodyBytesSent: 212, Latency: 5.007},
+			map[string]string{
+				"mode": "tcp", "frontend": "fnt", "backend": "bck", "server": "srv1", "termination_state": "cD",
+				"time_queue_ms": "0", "time_connect_ms": "0", "time_total_ms": "5007",
+				"actconn": "0", "feconn": "0", "beconn": "0", "srv_conn": "0", "retries": "3", "srv_queue": "0", "backend_queue": "0",
+			},
+			time.Date(2009, 2, 6, 12, 12, 51, 443000000, time.Local),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := (&HAProxyParser{}).Parse(tt.line)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !got.TimeLocal.Equal(tt.time) {
+				t.Errorf("TimeLocal = %v; want %v", got.TimeLocal, tt.time)
+			}
+			extra := got.Extra
+			got.TimeLocal, got.Service, got.Extra = time.Time{}, "", nil
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Parse() = %+v; want %+v", *got, tt.want)
+			}
+			if !reflect.DeepEqual(map[string]string(extra), tt.extra) {
+				t.Errorf("Parse() extra = %v; want %v", extra, tt.extra)
+			}
+		})
+	}
+}
+
+func TestHAProxyParserLocalTime(t *testing.T) {
+	// HAProxy logs the local time without an offset
+	local := time.Local
+	time.Local = time.FixedZone("CET", 3600)
+	defer func() { time.Local = local }()
+
+	got, err := (&HAProxyParser{}).Parse(`10.0.1.4:50000 [06/Feb/2009:12:12:51.443] fnt bck/srv1 0/0/5007 212 cD 0/0/0/0/3 0/0`)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if want := time.Date(2009, 2, 6, 11, 12, 51, 443000000, time.UTC); !got.TimeLocal.Equal(want) {
+		t.Errorf("TimeLocal = %v; want %v", got.TimeLocal, want)
+	}
+}
@@ -0,0 +1,45 @@
+package parser
+
+// IngressNginxFormat is the Kubernetes ingress-nginx controller's default
+// log format ("log-format-upstream"). $proxy_upstream_name is
+// "<namespace>-<service>-<port>"; $namespace, $ingress_name, $service_name
+// and $service_port can be added to the format to get them separately.
+const IngressNginxFormat = `$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent "$http_referer" "$http_user_agent" $request_length $request_time [$proxy_upstream_name] [$proxy_alternative_upstream_name] $upstream_addr $upstream_response_length $upstream_response_time $upstream_status $req_id`
+
+// IngressNginxParser parses ingress-nginx access logs, in the default or
+// a custom log-format-upstream
+type IngressNginxParser struct {
+	format *NginxFormatParser
+}
+
+// NewIngressNginxParser compiles format, or IngressNginxFormat if empty
+func NewIngressNginxParser(format string) (*IngressNginxParser, error) {
+	if format == "" {
+		format = IngressNginxFormat
+	}
+	p, err := NewNginxFormatParser(format)
+	if err != nil {
+		return nil, err
+	}
+	return &IngressNginxParser{format: p}, nil
+}
+
+// Parse implements LogParser
+func (p *IngressNginxParser) Parse(line string) (*GenericLogEntry, error) {
+	entry, err := p.format.Parse(line)
+	if err != nil {
+		return nil, err
+	}
+	entry.Service = "ingress-nginx"
+	if id, ok := entry.Extra["req_id"]; ok {
+		entry.RequestID = id
+		delete(entry.Extra, "req_id")
+	}
+	// Empty values ("[]", "-") carry nothing
+	for key, value := range entry.Extra {
+		if value == "" || value == "-" {
+			delete(entry.Extra, key)
+		}
+	}
+	return entry, nil
+}
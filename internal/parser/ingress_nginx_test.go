@@ -0,0 +1,61 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestIngressNginxParser(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		line   string
+		want   GenericLogEntry
+		extra  map[string]string
+		time   time.Time
+	}{
+		{
+			"Default format",
+			"",
+			`10.0.0.1 - - [12/Dec/2023:14:00:00 +0000] "GET /api/v1 HTTP/2.0" 200 512 "-" "curl/8.0" 54 0.012 [shop-web-svc-80] [] 10.244.1.7:8080 512 0.010 200 5d0c4d1b8f9e7a6b5c4d3e2f1a0b9c8d`,
+			GenericLogEntry{RemoteIP: "10.0.0.1", Method: "GET", Path: "/api/v1", Protocol: "HTTP/2.0", Status: 200, BodyBytesSent: 512, UserAgent: "curl/8.0", Latency: 0.012,
+				RequestID: "5d0c4d1b8f9e7a6b5c4d3e2f1a0b9c8d", RequestBytes: 54, UpstreamAddr: "10.244.1.7:8080", UpstreamLatency: 0.01},
+			map[string]string{"proxy_upstream_name": "shop-web-svc-80", "upstream_response_length": "512", "upstream_status": "200"},
+			time.Date(2023, 12, 12, 14, 0, 0, 0, time.UTC),
+		},
+		{
+			"Custom format with Kubernetes names",
+			IngressNginxFormat + ` $namespace $ingress_name $service_name $service_port`,
+			`10.0.0.2 - - [12/Dec/2023:14:00:00 +0000] "POST /login HTTP/1.1" 503 190 "-" "curl/8.0" 80 0.001 [shop-web-svc-80] [] - - - - 0a1b2c shop web web-svc 80`,
+			GenericLogEntry{RemoteIP: "10.0.0.2", Method: "POST", Path: "/login", Protocol: "HTTP/1.1", Status: 503, BodyBytesSent: 190, UserAgent: "curl/8.0", Latency: 0.001,
+				RequestID: "0a1b2c", RequestBytes: 80},
+			map[string]string{"proxy_upstream_name": "shop-web-svc-80", "namespace": "shop", "ingress_name": "web", "service_name": "web-svc", "service_port": "80"},
+			time.Date(2023, 12, 12, 14, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewIngressNginxParser(tt.format)
+			if err != nil {
+				t.Fatalf("NewIngressNginxParser() error = %v", err)
+			}
+			got, err := p.Parse(tt.line)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !got.TimeLocal.Equal(tt.time) {
+				t.Errorf("TimeLocal = %v; want %v", got.TimeLocal, tt.time)
+			}
+			extra := got.Extra
+			got.TimeLocal, got.Service, got.Extra = time.Time{}, "", nil
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Parse() = %+v; want %+v", *got, tt.want)
+			}
+			if !reflect.DeepEqual(map[string]string(extra), tt.extra) {
+				t.Errorf("Parse() extra = %v; want %v", extra, tt.extra)
+			}
+		})
+	}
+}
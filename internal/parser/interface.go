@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -18,9 +20,78 @@ type GenericLogEntry struct {
 	UserAgent     string
 	Service       string // e.g. "nginx", "apache", "caddy"
 	Latency       float64 // Request processing time in seconds
+
+	// Proxy / virtual host details, when the log format records them
+	Host            string   // Host header / authority / vhost
+	RequestID       string
+	RequestBytes    int      // Bytes received, including request line and headers
+	ForwardedFor    []string // X-Forwarded-For chain, client first
+	UpstreamAddr    string   // Backend that served the request (last one if retried)
+	UpstreamLatency float64  // Time spent waiting on upstreams, in seconds
+	TLSProtocol     string   // e.g. "TLSv1.3"
+	TLSCipher       string
+
+	Extra Fields // Format-specific values without a dedicated field (may be nil)
+}
+
+// Fields holds parser-specific values keyed by name. Names follow nginx
+// variable naming where there is an equivalent ("upstream_status",
+// "http_x_api_key", "server_port", ...).
+type Fields map[string]string
+
+// Get returns the value of key, or "" if unset (safe on a nil map)
+func (f Fields) Get(key string) string {
+	return f[key]
+}
+
+// SetExtra records a format-specific value, allocating Extra on first use
+func (e *GenericLogEntry) SetExtra(key, value string) {
+	if e.Extra == nil {
+		e.Extra = make(Fields)
+	}
+	e.Extra[key] = value
 }
 
 // LogParser interface that all specific parsers must implement
 type LogParser interface {
 	Parse(line string) (*GenericLogEntry, error)
 }
+
+// splitForwardedFor splits an X-Forwarded-For value into its hops,
+// dropping empty and placeholder ("-", "unknown") entries
+func splitForwardedFor(value string) []string {
+	var hops []string
+	for _, hop := range strings.Split(value, ",") {
+		hop = strings.TrimSpace(hop)
+		if hop == "" || hop == "-" || strings.EqualFold(hop, "unknown") {
+			continue
+		}
+		hops = append(hops, hop)
+	}
+	return hops
+}
+
+// sumLatencies adds up per-upstream times such as nginx's
+// "0.010, 0.200 : 0.005" (one value per upstream tried)
+func sumLatencies(value string) float64 {
+	var total float64
+	for _, f := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ':' || r == ' ' }) {
+		if v, err := strconv.ParseFloat(f, 64); err == nil {
+			total += v
+		}
+	}
+	return total
+}
+
+// lastUpstream returns the final entry of an nginx-style upstream list
+// ("10.0.0.1:80, 10.0.0.2:80 : 10.0.0.3:80")
+func lastUpstream(value string) string {
+	start := 0
+	if i := strings.LastIndex(value, ", "); i >= 0 {
+		start = i + 2
+	}
+	if i := strings.LastIndex(value, " : "); i >= 0 && i+3 > start {
+		start = i + 3
+	}
+	return value[start:]
+}
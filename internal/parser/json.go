@@ -0,0 +1,219 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JSONConfig defines a parser for JSON access logs, typically from the
+// config file
+type JSONConfig struct {
+	Name string
+
+	// Fields maps paths into the JSON object onto GenericLogEntry fields
+	// (see FieldTargets). Paths are dotted keys with optional array
+	// indexes, e.g. "request.headers.User-Agent[0]"; a leading "$." is
+	// accepted. Values at unmapped paths are ignored.
+	Fields map[string]string
+
+	// TimeLayout as for GrokConfig. Numeric timestamps are read as epoch
+	// seconds (or milliseconds/nanoseconds by magnitude) when empty.
+	TimeLayout string
+}
+
+// jsonDefaultFields maps the top-level keys commonly used by JSON access
+// logs (nginx escape=json formats, Go and Node services); used by the
+// built-in "json" parser
+var jsonDefaultFields = map[string]string{
+	"remote_addr":            "remote_ip",
+	"remote_ip":              "remote_ip",
+	"client_ip":              "remote_ip",
+	"remote_user":            "remote_user",
+	"time":                   "time",
+	"timestamp":              "time",
+	"ts":                     "time",
+	"@timestamp":             "time",
+	"time_local":             "time",
+	"time_iso8601":           "time",
+	"method":                 "method",
+	"request_method":         "method",
+	"path":                   "path",
+	"uri":                    "path",
+	"request_uri":            "path",
+	"url":                    "path",
+	"request":                "request_line",
+	"protocol":               "protocol",
+	"server_protocol":        "protocol",
+	"status":                 "status",
+	"status_code":            "status",
+	"bytes_sent":             "bytes_sent",
+	"body_bytes_sent":        "bytes_sent",
+	"size":                   "bytes_sent",
+	"request_length":         "request_bytes",
+	"user_agent":             "user_agent",
+	"http_user_agent":        "user_agent",
+	"referer":                "referer",
+	"http_referer":           "referer",
+	"host":                   "host",
+	"request_id":             "request_id",
+	"http_x_forwarded_for":   "forwarded_for",
+	"request_time":           "latency",
+	"duration":               "latency",
+	"duration_ms":            "latency_ms",
+	"upstream_addr":          "upstream_addr",
+	"upstream_response_time": "upstream_latency",
+}
+
+// JSONParser extracts configured paths from one JSON object per line
+type JSONParser struct {
+	Name   string
+	paths  []jsonPath
+	mapper *fieldMapper
+}
+
+type jsonPath struct {
+	source string
+	steps  []jsonStep
+}
+
+// jsonStep is an object key, or an array index if key is empty
+type jsonStep struct {
+	key   string
+	index int
+}
+
+// NewJSONParser validates cfg and compiles its paths
+func NewJSONParser(cfg JSONConfig) (*JSONParser, error) {
+	if len(cfg.Fields) == 0 {
+		return nil, fmt.Errorf("no fields defined")
+	}
+	mapper, err := newFieldMapper(cfg.Fields, cfg.TimeLayout)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &JSONParser{Name: cfg.Name, mapper: mapper}
+	for source := range cfg.Fields {
+		steps, err := parseJSONPath(source)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %v", source, err)
+		}
+		p.paths = append(p.paths, jsonPath{source, steps})
+	}
+	// Deterministic order when several paths feed the same field
+	sort.Slice(p.paths, func(i, j int) bool { return p.paths[i].source < p.paths[j].source })
+	return p, nil
+}
+
+func parseJSONPath(path string) ([]jsonStep, error) {
+	path = strings.TrimPrefix(path, "$.")
+	var steps []jsonStep
+	for _, segment := range strings.Split(path, ".") {
+		key, rest, _ := strings.Cut(segment, "[")
+		if key == "" && rest == "" {
+			return nil, fmt.Errorf("empty key in path")
+		}
+		if key != "" {
+			steps = append(steps, jsonStep{key: key})
+		}
+		for rest != "" {
+			n, tail, ok := strings.Cut(rest, "]")
+			i, err := strconv.Atoi(n)
+			if !ok || err != nil || i < 0 {
+				return nil, fmt.Errorf("invalid array index [%s", rest)
+			}
+			steps = append(steps, jsonStep{index: i})
+			if tail == "" {
+				break
+			}
+			if tail[0] != '[' {
+				return nil, fmt.Errorf("unexpected %q after array index", tail)
+			}
+			rest = tail[1:]
+		}
+	}
+	return steps, nil
+}
+
+// Parse implements LogParser
+func (p *JSONParser) Parse(line string) (*GenericLogEntry, error) {
+	dec := json.NewDecoder(strings.NewReader(line))
+	dec.UseNumber()
+	var doc map[string]interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s json: %v", p.Name, err)
+	}
+
+	entry := &GenericLogEntry{Service: p.Name}
+	found := false
+	for _, path := range p.paths {
+		v, ok := lookupJSON(doc, path.steps)
+		if !ok || v == nil {
+			continue
+		}
+		found = true
+		p.mapper.set(entry, path.source, jsonString(v))
+	}
+	if !found {
+		return nil, fmt.Errorf("no %s fields found in line", p.Name)
+	}
+	return entry, nil
+}
+
+func lookupJSON(v interface{}, steps []jsonStep) (interface{}, bool) {
+	for _, step := range steps {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			if step.key == "" {
+				return nil, false
+			}
+			var ok bool
+			if v, ok = node[step.key]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			if step.key != "" || step.index >= len(node) {
+				return nil, false
+			}
+			v = node[step.index]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// jsonString renders a JSON value as a field value. Arrays of scalars
+// (e.g. Go http.Header values) are joined with ", ".
+func jsonString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			if _, nested := item.(map[string]interface{}); nested {
+				return compactJSON(v)
+			}
+			parts = append(parts, jsonString(item))
+		}
+		return strings.Join(parts, ", ")
+	}
+	return compactJSON(v)
+}
+
+func compactJSON(v interface{}) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(v)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestJSONParser(t *testing.T) {
+	p, err := NewJSONParser(JSONConfig{
+		Name: "kong",
+		Fields: map[string]string{
+			"$.client_ip":                     "remote_ip",
+			"started_at":                      "time",
+			"request.method":                  "method",
+			"request.uri":                     "path",
+			"request.headers.user-agent[0]":   "user_agent",
+			"request.headers.x-forwarded-for": "forwarded_for",
+			"response.status":                 "status",
+			"response.size":                   "bytes_sent",
+			"latencies.request":               "latency_ms",
+			"route.name":                      "extra",
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewJSONParser() error = %v", err)
+	}
+
+	line := `{"client_ip":"10.0.0.1","started_at":1702389600123,"request":{"method":"GET","uri":"/x?y=1","headers":{"user-agent":["curl/8.0"],"x-forwarded-for":["1.2.3.4","10.0.0.9"]}},"response":{"status":200,"size":512},"latencies":{"request":25},"route":{"name":"api"}}`
+	got, err := p.Parse(line)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if want := time.UnixMilli(1702389600123); !got.TimeLocal.Equal(want) {
+		t.Errorf("TimeLocal = %v; want %v", got.TimeLocal, want)
+	}
+	if got.Extra.Get("route.name") != "api" {
+		t.Errorf("Extra = %v; want route.name=api", got.Extra)
+	}
+	got.TimeLocal, got.Extra = time.Time{}, nil
+	want := GenericLogEntry{RemoteIP: "10.0.0.1", Method: "GET", Path: "/x?y=1", UserAgent: "curl/8.0", ForwardedFor: []string{"1.2.3.4", "10.0.0.9"},
+		Status: 200, BodyBytesSent: 512, Latency: 0.025, Service: "kong"}
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("Parse() = %+v; want %+v", *got, want)
+	}
+
+	for _, line := range []string{`not json`, `{"other":1}`, `[1,2]`} {
+		if _, err := p.Parse(line); err == nil {
+			t.Errorf("Parse(%s) error = nil; want error", line)
+		}
+	}
+}
+
+func TestParseJSONPath(t *testing.T) {
+	for _, path := range []string{"a..b", "a[x]", "a[0]b"} {
+		if _, err := parseJSONPath(path); err == nil {
+			t.Errorf("parseJSONPath(%q) error = nil; want error", path)
+		}
+	}
+	got, err := parseJSONPath("a.b[1][2]")
+	want := []jsonStep{{key: "a"}, {key: "b"}, {index: 1}, {index: 2}}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("parseJSONPath() = %v, %v; want %v", got, err, want)
+	}
+}
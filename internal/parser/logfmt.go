@@ -0,0 +1,133 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LogfmtConfig defines a parser for logfmt / key=value access logs
+type LogfmtConfig struct {
+	Name string
+
+	// Fields maps keys onto GenericLogEntry fields (see FieldTargets), on top
+	// of logfmtDefaultFields. Unmapped keys are kept in Extra.
+	Fields map[string]string
+
+	// TimeLayout as for GrokConfig
+	TimeLayout string
+}
+
+// logfmtDefaultFields covers the keys commonly used by Go HTTP middleware
+// and Heroku-style routers
+var logfmtDefaultFields = map[string]string{
+	"remote_addr": "remote_ip",
+	"client_ip":   "remote_ip",
+	"ip":          "remote_ip",
+	"ts":          "time",
+	"t":           "time",
+	"uri":         "path",
+	"url":         "path",
+	"proto":       "protocol",
+	"code":        "status",
+	"bytes":       "bytes_sent",
+	"size":        "bytes_sent",
+	"duration":    "latency",
+	"took":        "latency",
+	"elapsed":     "latency",
+	"fwd":         "forwarded_for",
+	"ua":          "user_agent",
+}
+
+// LogfmtParser parses `method=GET path=/x status=200 duration=12ms` lines
+type LogfmtParser struct {
+	Name   string
+	mapper *fieldMapper
+}
+
+// NewLogfmtParser validates the field mapping of cfg
+func NewLogfmtParser(cfg LogfmtConfig) (*LogfmtParser, error) {
+	fields := make(map[string]string, len(logfmtDefaultFields)+len(cfg.Fields))
+	for k, v := range logfmtDefaultFields {
+		fields[k] = v
+	}
+	for k, v := range cfg.Fields {
+		fields[k] = v
+	}
+	mapper, err := newFieldMapper(fields, cfg.TimeLayout)
+	if err != nil {
+		return nil, err
+	}
+	return &LogfmtParser{Name: cfg.Name, mapper: mapper}, nil
+}
+
+// Parse implements LogParser. Lines without a method or status are
+// rejected, as they are not request logs.
+func (p *LogfmtParser) Parse(line string) (*GenericLogEntry, error) {
+	entry := &GenericLogEntry{Service: p.Name}
+	for _, kv := range splitLogfmt(line) {
+		p.mapper.set(entry, kv[0], kv[1])
+	}
+	if entry.Method == "" && entry.Status == 0 {
+		return nil, fmt.Errorf("not a %s request line: %s", p.Name, line)
+	}
+	if entry.RemoteIP == "" && len(entry.ForwardedFor) > 0 {
+		entry.RemoteIP = entry.ForwardedFor[0] // Routers often only log fwd=
+	}
+	return entry, nil
+}
+
+// splitLogfmt returns the key/value pairs of a logfmt line. Values may be
+// double-quoted with backslash escapes; keys without "=" get an empty value.
+func splitLogfmt(line string) [][2]string {
+	var pairs [][2]string
+	i := 0
+	for i < len(line) {
+		for i < len(line) && line[i] == ' ' {
+			i++
+		}
+		start := i
+		for i < len(line) && line[i] != '=' && line[i] != ' ' {
+			i++
+		}
+		key := line[start:i]
+		if i >= len(line) || line[i] != '=' {
+			if key != "" {
+				pairs = append(pairs, [2]string{key, ""})
+			}
+			continue
+		}
+		i++ // '='
+
+		var value string
+		if i < len(line) && line[i] == '"' {
+			var b strings.Builder
+			for i++; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' && i+1 < len(line) {
+					i++
+					switch line[i] {
+					case 'n':
+						b.WriteByte('\n')
+					case 't':
+						b.WriteByte('\t')
+					default:
+						b.WriteByte(line[i])
+					}
+					continue
+				}
+				b.WriteByte(line[i])
+			}
+			i++ // Closing quote
+			value = b.String()
+		} else {
+			start = i
+			for i < len(line) && line[i] != ' ' {
+				i++
+			}
+			value = line[start:i]
+		}
+		if key != "" {
+			pairs = append(pairs, [2]string{key, value})
+		}
+	}
+	return pairs
+}
@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLogfmtParser(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[string]string
+		line   string
+		want   GenericLogEntry
+		extra  map[string]string
+		time   time.Time
+	}{
+		{
+			"Heroku router",
+			map[string]string{"service": "latency"}, // Time spent in the app
+			`at=info method=GET path="/x?a=1" host=app.example.com request_id=abc fwd="1.2.3.4,10.0.0.9" dyno=web.1 connect=1ms service=12ms status=200 bytes=512 protocol=https`,
+			GenericLogEntry{RemoteIP: "1.2.3.4", Method: "GET", Path: "/x?a=1", Protocol: "https", Status: 200, BodyBytesSent: 512, Latency: 0.012,
+				Host: "app.example.com", RequestID: "abc", ForwardedFor: []string{"1.2.3.4", "10.0.0.9"}},
+			map[string]string{"at": "info", "dyno": "web.1", "connect": "1ms"},
+			time.Time{},
+		},
+		{
+			"Custom mapping and escapes",
+			map[string]string{"peer": "remote_ip", "rt": "latency_ms", "msg": "-"},
+			`level=info msg="request \"done\"" peer=10.0.0.1 method=POST uri=/api code=503 rt=250 debug`,
+			GenericLogEntry{RemoteIP: "10.0.0.1", Method: "POST", Path: "/api", Status: 503, Latency: 0.25},
+			map[string]string{"level": "info", "debug": ""},
+			time.Time{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewLogfmtParser(LogfmtConfig{Fields: tt.fields})
+			if err != nil {
+				t.Fatalf("NewLogfmtParser() error = %v", err)
+			}
+			got, err := p.Parse(tt.line)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !got.TimeLocal.Equal(tt.time) {
+				t.Errorf("TimeLocal = %v; want %v", got.TimeLocal, tt.time)
+			}
+			extra := got.Extra
+			got.TimeLocal, got.Service, got.Extra = time.Time{}, "", nil
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Parse() = %+v; want %+v", *got, tt.want)
+			}
+			if !reflect.DeepEqual(map[string]string(extra), tt.extra) {
+				t.Errorf("Parse() extra = %v; want %v", extra, tt.extra)
+			}
+		})
+	}
+
+	p, _ := NewLogfmtParser(LogfmtConfig{})
+	if _, err := p.Parse(`level=info msg="cache warmed"`); err == nil {
+		t.Error("Parse(non-request line) error = nil; want error")
+	}
+}
@@ -0,0 +1,126 @@
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type MailEventType int
+
+const (
+	MailAuthSuccess MailEventType = iota
+	MailAuthFailed
+	MailReject // Postfix NOQUEUE: reject
+)
+
+// MailLogEntry is a Postfix or Dovecot authentication or reject event
+type MailLogEntry struct {
+	Type     MailEventType
+	Service  string // smtp, imap, pop3, submission, ...
+	User     string // "" if not logged
+	IP       string
+	Method   string // SASL mechanism: PLAIN, LOGIN, ...
+	Attempts int    // Failed attempts the line stands for (Dovecot counts them per connection)
+	Reason   string // MailReject: e.g. "Relay access denied"
+	Time     time.Time
+}
+
+var (
+	// warning: unknown[203.0.113.9]: SASL LOGIN authentication failed: UGFzc3dvcmQ6
+	// warning: host[203.0.113.9]: SASL PLAIN authentication failed: (reason unavailable), sasl_username=alice@example.com
+	postfixSASLFailedRegex = regexp.MustCompile(`^warning: [^\[\s]*\[([^\]]+)\]: SASL (\S+) authentication failed(?:.*sasl_username=(\S+))?`)
+
+	// 1A2B3C4D5E: client=host[198.51.100.2], sasl_method=PLAIN, sasl_username=alice@example.com
+	postfixSASLLoginRegex = regexp.MustCompile(`client=[^\[\s]*\[([^\]]+)\](?::\d+)?, sasl_method=([^,\s]+), sasl_username=(\S+)`)
+
+	// NOQUEUE: reject: RCPT from unknown[203.0.113.9]: 554 5.7.1 <x@example.com>: Relay access denied; from=<a@b> to=<x@example.com> proto=ESMTP helo=<x>
+	// Groups: 1 IP, 2 message
+	postfixRejectRegex = regexp.MustCompile(`NOQUEUE: reject: \S+ from [^\[\s]*\[([^\]]+)\](?::\d+)?: \d{3} (?:\d\.\d+\.\d+ )?(.*)$`)
+
+	// <x@example.com>: leading the reject message
+	postfixRejectAddrRegex = regexp.MustCompile(`^<[^>]*>: `)
+
+	// ... blocked using zen.spamhaus.org; ...
+	postfixRBLRegex = regexp.MustCompile(`blocked using ([^\s;,]+)`)
+
+	// imap-login: Disconnected (auth failed, 3 attempts in 12 secs): user=<alice>, method=PLAIN, rip=203.0.113.9, lip=10.0.0.5, TLS, session=<x>
+	// pop3-login: Aborted login (auth failed, 1 attempts in 2 secs): user=<bob>, method=PLAIN, rip=203.0.113.9, ...
+	dovecotFailedRegex = regexp.MustCompile(`^(\w+)-login: .*\(auth failed, (\d+) attempts?`)
+
+	// imap-login: Login: user=<alice>, method=PLAIN, rip=198.51.100.2, lip=10.0.0.5, mpid=123, TLS, session=<x>
+	dovecotLoginRegex = regexp.MustCompile(`^(\w+)-login: Login: `)
+
+	// user=<alice>, method=PLAIN, rip=203.0.113.9
+	dovecotFieldRegex = regexp.MustCompile(`\b(user|method|rip)=(<[^>]*>|[^,\s]*)`)
+)
+
+// ParseMailLine recognizes the Postfix (SASL failures and logins, NOQUEUE
+// rejects) and Dovecot (logins, auth failed) lines of a mail log. Returns
+// nil for other lines.
+func ParseMailLine(line string) *MailLogEntry {
+	h, ok := ParseSyslogHeader(line)
+	if !ok {
+		return nil
+	}
+	entry := &MailLogEntry{Time: h.Time, Attempts: 1}
+
+	switch {
+	case strings.HasPrefix(h.Program, "postfix"):
+		entry.Service = "smtp"
+		if strings.Contains(h.Program, "/submission") { // postfix/submission/smtpd
+			entry.Service = "submission"
+		}
+		if m := postfixSASLFailedRegex.FindStringSubmatch(h.Message); m != nil {
+			entry.Type, entry.IP, entry.Method, entry.User = MailAuthFailed, m[1], m[2], m[3]
+			return entry
+		}
+		if m := postfixSASLLoginRegex.FindStringSubmatch(h.Message); m != nil {
+			entry.Type, entry.IP, entry.Method, entry.User = MailAuthSuccess, m[1], m[2], m[3]
+			return entry
+		}
+		if m := postfixRejectRegex.FindStringSubmatch(h.Message); m != nil {
+			entry.Type, entry.IP, entry.Reason = MailReject, m[1], postfixRejectReason(m[2])
+			return entry
+		}
+
+	case strings.HasPrefix(h.Program, "dovecot"):
+		msg := h.Message
+		if m := dovecotFailedRegex.FindStringSubmatch(msg); m != nil {
+			entry.Type, entry.Service = MailAuthFailed, m[1]
+			entry.Attempts, _ = strconv.Atoi(m[2])
+		} else if m := dovecotLoginRegex.FindStringSubmatch(msg); m != nil {
+			entry.Type, entry.Service = MailAuthSuccess, m[1]
+		} else {
+			return nil
+		}
+		for _, f := range dovecotFieldRegex.FindAllStringSubmatch(msg, -1) {
+			value := strings.Trim(f[2], "<>")
+			switch f[1] {
+			case "user":
+				entry.User = value
+			case "method":
+				entry.Method = value
+			case "rip":
+				entry.IP = value
+			}
+		}
+		return entry
+	}
+	return nil
+}
+
+// postfixRejectReason reduces a reject message to a label value: the RBL
+// for DNSBL rejects, else the text before addresses and details
+// ("Recipient address rejected: User unknown in local recipient table")
+func postfixRejectReason(msg string) string {
+	if m := postfixRBLRegex.FindStringSubmatch(msg); m != nil {
+		return "blocked using " + m[1]
+	}
+	msg = postfixRejectAddrRegex.ReplaceAllString(msg, "")
+	if i := strings.IndexAny(msg, ";,["); i >= 0 {
+		msg = msg[:i]
+	}
+	return strings.TrimSpace(msg)
+}
@@ -0,0 +1,81 @@
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseMailLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want *MailLogEntry
+	}{
+		{
+			"Postfix SASL failure",
+			"Dec 12 14:00:00 mx1 postfix/smtpd[1234]: warning: unknown[203.0.113.9]: SASL LOGIN authentication failed: UGFzc3dvcmQ6",
+			&MailLogEntry{Type: MailAuthFailed, Service: "smtp", IP: "203.0.113.9", Method: "LOGIN", Attempts: 1},
+		},
+		{
+			"Postfix SASL failure with username",
+			"Dec 12 14:00:00 mx1 postfix/submission/smtpd[1235]: warning: host.example.net[198.51.100.3]: SASL PLAIN authentication failed: (reason unavailable), sasl_username=alice@example.com",
+			&MailLogEntry{Type: MailAuthFailed, Service: "submission", User: "alice@example.com", IP: "198.51.100.3", Method: "PLAIN", Attempts: 1},
+		},
+		{
+			"Postfix SASL login",
+			"Dec 12 14:00:00 mx1 postfix/smtpd[1236]: 1A2B3C4D5E: client=mail.example.com[198.51.100.2], sasl_method=PLAIN, sasl_username=bob@example.com",
+			&MailLogEntry{Type: MailAuthSuccess, Service: "smtp", User: "bob@example.com", IP: "198.51.100.2", Method: "PLAIN", Attempts: 1},
+		},
+		{
+			"Postfix relay denied",
+			"Dec 12 14:00:00 mx1 postfix/smtpd[1237]: NOQUEUE: reject: RCPT from unknown[203.0.113.9]: 554 5.7.1 <x@example.org>: Relay access denied; from=<a@example.net> to=<x@example.org> proto=ESMTP helo=<spam>",
+			&MailLogEntry{Type: MailReject, Service: "smtp", IP: "203.0.113.9", Reason: "Relay access denied", Attempts: 1},
+		},
+		{
+			"Postfix client host rejected",
+			"Dec 12 14:00:00 mx1 postfix/smtpd[1237]: NOQUEUE: reject: RCPT from unknown[203.0.113.9]: 450 4.7.1 Client host rejected: cannot find your reverse hostname, [203.0.113.9]; from=<a@example.net> to=<x@example.org> proto=ESMTP helo=<spam>",
+			&MailLogEntry{Type: MailReject, Service: "smtp", IP: "203.0.113.9", Reason: "Client host rejected: cannot find your reverse hostname", Attempts: 1},
+		},
+		{
+			"Postscreen DNSBL",
+			"Dec 12 14:00:00 mx1 postfix/postscreen[99]: NOQUEUE: reject: RCPT from [203.0.113.9]:50000: 550 5.7.1 Service unavailable; client [203.0.113.9] blocked using zen.spamhaus.org; from=<a@example.net>, to=<x@example.org>, proto=ESMTP, helo=<spam>",
+			&MailLogEntry{Type: MailReject, Service: "smtp", IP: "203.0.113.9", Reason: "blocked using zen.spamhaus.org", Attempts: 1},
+		},
+		{
+			"Dovecot auth failed",
+			"Dec 12 14:00:00 mx1 dovecot: imap-login: Disconnected (auth failed, 3 attempts in 12 secs): user=<alice>, method=PLAIN, rip=203.0.113.9, lip=10.0.0.5, TLS, session=<abc>",
+			&MailLogEntry{Type: MailAuthFailed, Service: "imap", User: "alice", IP: "203.0.113.9", Method: "PLAIN", Attempts: 3},
+		},
+		{
+			"Dovecot 2.3 aborted login",
+			"Dec 12 14:00:00 mx1 dovecot[800]: pop3-login: Disconnected: Connection closed (auth failed, 1 attempts in 2 secs): user=<bob>, method=LOGIN, rip=203.0.113.10, lip=10.0.0.5, session=<def>",
+			&MailLogEntry{Type: MailAuthFailed, Service: "pop3", User: "bob", IP: "203.0.113.10", Method: "LOGIN", Attempts: 1},
+		},
+		{
+			"Dovecot login",
+			"Dec 12 14:00:00 mx1 dovecot: imap-login: Login: user=<alice>, method=PLAIN, rip=198.51.100.2, lip=10.0.0.5, mpid=4242, TLS, session=<ghi>",
+			&MailLogEntry{Type: MailAuthSuccess, Service: "imap", User: "alice", IP: "198.51.100.2", Method: "PLAIN", Attempts: 1},
+		},
+		{"Postfix delivery", "Dec 12 14:00:00 mx1 postfix/smtp[1300]: 1A2B3C4D5E: to=<x@example.org>, relay=mx.example.org[192.0.2.1]:25, status=sent (250 OK)", nil},
+		{"Other program", "Dec 12 14:00:00 mx1 sshd[1]: Accepted password for root from 1.2.3.4 port 22 ssh2", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseMailLine(tt.line)
+			if got == nil || tt.want == nil {
+				if got != tt.want {
+					t.Errorf("ParseMailLine() = %+v; want %+v", got, tt.want)
+				}
+				return
+			}
+			if got.Time.Month() != time.December {
+				t.Errorf("Time = %v", got.Time)
+			}
+			got.Time = time.Time{}
+			if *got != *tt.want {
+				t.Errorf("ParseMailLine() = %+v; want %+v", *got, *tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NginxFormatParser parses lines written with a custom nginx log_format.
+// It is generated from the format string itself: every $variable becomes a
+// capture group, well-known variables ($status, $host, $upstream_addr, ...)
+// are mapped onto GenericLogEntry and all others are kept in Extra under
+// their name (without the $).
+type NginxFormatParser struct {
+	Format string
+	re     *regexp.Regexp
+	vars   []string // Variable captured by each group, in order
+}
+
+// nginxVarPatterns are the shapes of variables whose values can contain the
+// separator that follows them (e.g. spaces), or that are commonly unquoted.
+var nginxVarPatterns = map[string]string{
+	"remote_addr":         `\S+`,
+	"remote_user":         `\S+`,
+	"time_local":          `\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}`,
+	"time_iso8601":        `\S+`,
+	"msec":                `[\d.]+`,
+	"status":              `\d{3}|-`,
+	"body_bytes_sent":     `\d+|-`,
+	"bytes_sent":          `\d+|-`,
+	"request_length":      `\d+|-`,
+	"request_time":        `[\d.]+|-`,
+	"connection":          `\d+|-`,
+	"connection_requests": `\d+|-`,
+}
+
+// nginxUpstreamPattern matches upstream_* values, which hold one entry per
+// upstream tried: "10.0.0.1:80, 10.0.0.2:80" or "0.010 : 0.002".
+const nginxUpstreamPattern = `[^\s,]+(?:(?:, | : )[^\s,]+)*`
+
+var nginxVarRegex = regexp.MustCompile(`^\$(?:\{(\w+)\}|(\w+))`)
+
+// NewNginxFormatParser compiles an nginx log_format string, e.g.
+// `$remote_addr - $remote_user [$time_local] "$request" $status ...`
+func NewNginxFormatParser(format string) (*NginxFormatParser, error) {
+	p := &NginxFormatParser{Format: format}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i := 0; i < len(format); {
+		m := nginxVarRegex.FindStringSubmatch(format[i:])
+		if m == nil {
+			// Literal text up to the next variable
+			j := strings.IndexByte(format[i+1:], '$')
+			if j < 0 {
+				j = len(format)
+			} else {
+				j += i + 1
+			}
+			expr.WriteString(regexp.QuoteMeta(format[i:j]))
+			i = j
+			continue
+		}
+
+		name := m[1] + m[2]
+		i += len(m[0])
+		expr.WriteString("(" + nginxVarPattern(name, format[i:]) + ")")
+		p.vars = append(p.vars, name)
+	}
+	expr.WriteString("$")
+
+	if len(p.vars) == 0 {
+		return nil, fmt.Errorf("log_format has no variables: %q", format)
+	}
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile log_format %q: %v", format, err)
+	}
+	p.re = re
+	return p, nil
+}
+
+// nginxVarPattern returns the regex for a variable given the format text
+// that follows it. Unknown variables extend up to the next literal character.
+func nginxVarPattern(name, rest string) string {
+	if pat, ok := nginxVarPatterns[name]; ok {
+		return pat
+	}
+	if strings.HasPrefix(name, "upstream_") {
+		return nginxUpstreamPattern
+	}
+	if rest == "" {
+		return `.*`
+	}
+	switch c := rest[0]; c {
+	case '$':
+		return `.*?` // Two variables back to back
+	case ' ', '\t':
+		return `\S*`
+	case '"':
+		return `(?:[^"\\]|\\.)*` // nginx escapes quotes as \x22 (or \" with escape=json)
+	default:
+		return `[^` + regexp.QuoteMeta(string(c)) + `]*`
+	}
+}
+
+// Parse implements LogParser
+func (p *NginxFormatParser) Parse(line string) (*GenericLogEntry, error) {
+	matches := p.re.FindStringSubmatch(line)
+	if matches == nil {
+		return nil, fmt.Errorf("failed to parse line: %s", line)
+	}
+
+	entry := &GenericLogEntry{Service: "nginx"}
+	for i, name := range p.vars {
+		value := matches[i+1]
+		if !setNginxVar(entry, name, value) {
+			entry.SetExtra(name, value)
+		}
+	}
+	return entry, nil
+}
+
+// setNginxVar maps a well-known variable onto entry. Returns false if the
+// variable has no dedicated field.
+func setNginxVar(entry *GenericLogEntry, name, value string) bool {
+	if value == "-" {
+		value = ""
+	}
+	switch name {
+	case "remote_addr":
+		entry.RemoteIP = value
+	case "remote_user":
+		entry.RemoteUser = value
+	case "time_local":
+		entry.TimeLocal, _ = time.Parse("02/Jan/2006:15:04:05 -0700", value)
+	case "time_iso8601":
+		entry.TimeLocal, _ = time.Parse(time.RFC3339, value)
+	case "msec":
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			sec := int64(f)
+			entry.TimeLocal = time.Unix(sec, int64((f-float64(sec))*1e9))
+		}
+	case "request":
+		// "GET /path HTTP/1.1"; malformed requests keep whatever parts exist
+		parts := strings.SplitN(value, " ", 3)
+		entry.Method = parts[0]
+		if len(parts) > 1 {
+			entry.Path = parts[1]
+		}
+		if len(parts) > 2 {
+			entry.Protocol = parts[2]
+		}
+	case "request_method":
+		entry.Method = value
+	case "request_uri":
+		entry.Path = value
+	case "server_protocol":
+		entry.Protocol = value
+	case "status":
+		entry.Status, _ = strconv.Atoi(value)
+	case "body_bytes_sent":
+		entry.BodyBytesSent, _ = strconv.Atoi(value)
+	case "http_referer":
+		entry.Referer = value
+	case "http_user_agent":
+		entry.UserAgent = value
+	case "request_time":
+		entry.Latency, _ = strconv.ParseFloat(value, 64)
+	case "request_length":
+		entry.RequestBytes, _ = strconv.Atoi(value)
+	case "host":
+		entry.Host = value
+	case "server_name":
+		if entry.Host == "" {
+			entry.Host = value // Until $host says otherwise
+		}
+		return false
+	case "request_id":
+		entry.RequestID = value
+	case "http_x_forwarded_for":
+		entry.ForwardedFor = splitForwardedFor(value)
+	case "upstream_addr":
+		entry.UpstreamAddr = lastUpstream(value)
+	case "upstream_response_time":
+		entry.UpstreamLatency = sumLatencies(value)
+	case "ssl_protocol":
+		entry.TLSProtocol = value
+	case "ssl_cipher":
+		entry.TLSCipher = value
+	default:
+		return false
+	}
+	return true
+}
+
+// logFormatDirective matches `log_format name [escape=...] 'part' "part" ...;`
+var logFormatDirective = regexp.MustCompile(`(?s)\blog_format\s+(\S+)\s+(?:escape=\S+\s+)?((?:'[^']*'|"[^"]*"|\s)+);`)
+
+var quotedPart = regexp.MustCompile(`'([^']*)'|"([^"]*)"`)
+
+var confComment = regexp.MustCompile(`(?m)^\s*#.*$`)
+
+// NginxCombinedFormat is nginx's predefined "combined" log_format
+const NginxCombinedFormat = `$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent "$http_referer" "$http_user_agent"`
+
+// LoadNginxFormat reads the log_format called name from an nginx config
+// file. Multi-line formats made of several quoted strings are joined the
+// way nginx does. "combined" is built in and needs no config file.
+func LoadNginxFormat(confPath, name string) (string, error) {
+	if name == "combined" {
+		return NginxCombinedFormat, nil
+	}
+	data, err := os.ReadFile(confPath)
+	if err != nil {
+		return "", err
+	}
+	conf := confComment.ReplaceAllString(string(data), "")
+	for _, m := range logFormatDirective.FindAllStringSubmatch(conf, -1) {
+		if m[1] != name {
+			continue
+		}
+		var format strings.Builder
+		for _, part := range quotedPart.FindAllStringSubmatch(m[2], -1) {
+			format.WriteString(part[1] + part[2])
+		}
+		return format.String(), nil
+	}
+	return "", fmt.Errorf("log_format %q not found in %s", name, confPath)
+}
@@ -0,0 +1,101 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNginxFormatParser(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		line   string
+		want   GenericLogEntry
+		extra  map[string]string
+		time   time.Time
+	}{
+		{
+			"Combined",
+			NginxCombinedFormat,
+			`10.0.0.1 - bob [12/Dec/2023:14:00:00 +0000] "GET /index.html?a=1 HTTP/1.1" 200 512 "-" "curl/8.0"`,
+			GenericLogEntry{RemoteIP: "10.0.0.1", RemoteUser: "bob", Method: "GET", Path: "/index.html?a=1", Protocol: "HTTP/1.1", Status: 200, BodyBytesSent: 512, UserAgent: "curl/8.0"},
+			nil,
+			time.Date(2023, 12, 12, 14, 0, 0, 0, time.UTC),
+		},
+		{
+			"Timings and extras",
+			`$remote_addr [$time_local] "$request" $status $body_bytes_sent $request_time $upstream_response_time $upstream_addr $upstream_status $host "$http_x_forwarded_for" $request_id`,
+			`10.0.0.2 [12/Dec/2023:14:00:00 +0000] "POST /api HTTP/2.0" 502 0 0.250 0.100, 0.150 10.1.0.1:80, 10.1.0.2:80 502, 502 api.example.com "1.2.3.4, 10.0.0.9" abc123`,
+			GenericLogEntry{RemoteIP: "10.0.0.2", Method: "POST", Path: "/api", Protocol: "HTTP/2.0", Status: 502, Latency: 0.25,
+				Host: "api.example.com", RequestID: "abc123", ForwardedFor: []string{"1.2.3.4", "10.0.0.9"}, UpstreamAddr: "10.1.0.2:80", UpstreamLatency: 0.25},
+			map[string]string{"upstream_status": "502, 502"},
+			time.Date(2023, 12, 12, 14, 0, 0, 0, time.UTC),
+		},
+		{
+			"Braced variable and escaped quote",
+			`${remote_addr}|$status|"$http_user_agent"`,
+			`10.0.0.3|404|"evil\x22agent"`,
+			GenericLogEntry{RemoteIP: "10.0.0.3", Status: 404, UserAgent: `evil\x22agent`},
+			nil,
+			time.Time{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewNginxFormatParser(tt.format)
+			if err != nil {
+				t.Fatalf("NewNginxFormatParser() error = %v", err)
+			}
+			got, err := p.Parse(tt.line)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !got.TimeLocal.Equal(tt.time) {
+				t.Errorf("TimeLocal = %v; want %v", got.TimeLocal, tt.time)
+			}
+			extra := got.Extra
+			got.TimeLocal, got.Service, got.Extra = time.Time{}, "", nil
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Parse() = %+v; want %+v", *got, tt.want)
+			}
+			if len(extra) != len(tt.extra) {
+				t.Errorf("Parse() extra = %v; want %v", extra, tt.extra)
+			}
+			for k, v := range tt.extra {
+				if extra[k] != v {
+					t.Errorf("Extra[%q] = %q; want %q", k, extra[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadNginxFormat(t *testing.T) {
+	conf := filepath.Join(t.TempDir(), "nginx.conf")
+	body := `http {
+    # log_format main 'commented out';
+    log_format main '$remote_addr - $remote_user [$time_local] '
+                    '"$request" $status';
+    log_format json escape=json '{"ip":"$remote_addr"}';
+}
+`
+	if err := os.WriteFile(conf, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadNginxFormat(conf, "main")
+	if want := `$remote_addr - $remote_user [$time_local] "$request" $status`; err != nil || got != want {
+		t.Errorf("LoadNginxFormat(main) = %q, %v; want %q", got, err, want)
+	}
+	got, err = LoadNginxFormat(conf, "json")
+	if want := `{"ip":"$remote_addr"}`; err != nil || got != want {
+		t.Errorf("LoadNginxFormat(json) = %q, %v; want %q", got, err, want)
+	}
+	if _, err := LoadNginxFormat(conf, "missing"); err == nil {
+		t.Error("LoadNginxFormat(missing) error = nil; want error")
+	}
+}
@@ -0,0 +1,137 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Privilege escalation results
+const (
+	PrivilegeSuccess     = "success"
+	PrivilegeFailed      = "failed"       // Wrong password
+	PrivilegeDenied      = "denied"       // Not allowed by sudoers
+	PrivilegeAuthFailure = "auth_failure" // pam_unix authentication failure
+)
+
+// PrivilegeEvent is a sudo, su or PAM authentication event of an auth log
+type PrivilegeEvent struct {
+	Program    string // sudo, su, ...
+	User       string // Who asked
+	TargetUser string // Who they wanted to be
+	Result     string // PrivilegeSuccess, ...
+	Reason     string // e.g. "3 incorrect password attempts", "user NOT in sudoers"
+	Command    string // sudo only
+	TTY        string
+	PWD        string
+	Time       time.Time
+}
+
+var (
+	// alice : TTY=pts/0 ; PWD=/home/alice ; USER=root ; COMMAND=/usr/bin/apt update
+	// bob : user NOT in sudoers ; TTY=pts/1 ; PWD=/home/bob ; USER=root ; COMMAND=/bin/cat /etc/shadow
+	sudoRegex = regexp.MustCompile(`^\s*(\S+) : (.*)$`)
+
+	// pam_unix(sudo:auth): authentication failure; logname=alice uid=1000 euid=0 tty=/dev/pts/0 ruser=alice rhost=  user=alice
+	pamAuthFailureRegex = regexp.MustCompile(`^pam_unix\(([\w-]+):auth\): authentication failure;(.*)$`)
+	pamFieldRegex       = regexp.MustCompile(`(\w+)=(\S*)`)
+
+	// pam_unix(su-l:session): session opened for user root(uid=0) by alice(uid=1000)
+	pamSuSessionRegex = regexp.MustCompile(`^pam_unix\((?:su|su-l|runuser|runuser-l):session\): session opened for user ([^\s(]+)(?:\(uid=\d+\))? by ([^\s(]*)`)
+
+	// FAILED SU (to root) alice on pts/0
+	// FAILED su for root by alice
+	suFailedRegex = regexp.MustCompile(`^FAILED (?:SU \(to (\S+)\) (\S+) on (\S+)|su for (\S+) by (\S+))`)
+)
+
+// privilegePAMServices are the PAM services whose authentication failures
+// are escalation attempts
+var privilegePAMServices = map[string]bool{
+	"sudo": true, "sudo-i": true, "su": true, "su-l": true, "runuser": true, "runuser-l": true, "polkit-1": true,
+}
+
+// ParsePrivilegeLine recognizes sudo, su and pam_unix authentication
+// failure lines of an auth log (auth.log, secure). Returns nil for other
+// lines.
+func ParsePrivilegeLine(line string) *PrivilegeEvent {
+	h, ok := ParseSyslogHeader(line)
+	if !ok {
+		return nil
+	}
+	event := &PrivilegeEvent{Program: h.Program, Time: h.Time}
+
+	if m := pamAuthFailureRegex.FindStringSubmatch(h.Message); m != nil {
+		if !privilegePAMServices[m[1]] {
+			return nil
+		}
+		fields := make(map[string]string)
+		for _, f := range pamFieldRegex.FindAllStringSubmatch(m[2], -1) {
+			fields[f[1]] = f[2]
+		}
+		event.Program, event.Result, event.Reason = m[1], PrivilegeAuthFailure, "authentication failure"
+		event.User = fields["ruser"]
+		if event.User == "" {
+			event.User = fields["logname"]
+		}
+		event.TargetUser = fields["user"]
+		event.TTY = fields["tty"]
+		return event
+	}
+
+	switch h.Program {
+	case "sudo":
+		m := sudoRegex.FindStringSubmatch(h.Message)
+		if m == nil {
+			return nil
+		}
+		event.User = m[1]
+		parseSudoFields(event, m[2])
+		if event.TargetUser == "" && event.Command == "" {
+			return nil
+		}
+		switch {
+		case event.Reason == "":
+			event.Result = PrivilegeSuccess
+		case strings.Contains(event.Reason, "incorrect password"):
+			event.Result = PrivilegeFailed
+		default:
+			event.Result = PrivilegeDenied
+		}
+		return event
+
+	case "su", "runuser":
+		if m := pamSuSessionRegex.FindStringSubmatch(h.Message); m != nil {
+			event.TargetUser, event.User, event.Result = m[1], m[2], PrivilegeSuccess
+			return event
+		}
+		if m := suFailedRegex.FindStringSubmatch(h.Message); m != nil {
+			event.TargetUser, event.User, event.TTY = m[1]+m[4], m[2]+m[5], m[3]
+			event.Result, event.Reason = PrivilegeFailed, "authentication failure"
+			return event
+		}
+	}
+	return nil
+}
+
+// parseSudoFields reads "reason ; TTY=pts/0 ; PWD=/ ; USER=root ; COMMAND=..."
+// into event. COMMAND comes last and is kept whole.
+func parseSudoFields(event *PrivilegeEvent, fields string) {
+	for fields != "" {
+		part, rest, _ := strings.Cut(fields, " ; ")
+		key, value, ok := strings.Cut(part, "=")
+		switch {
+		case !ok:
+			event.Reason = strings.TrimSpace(part)
+		case key == "COMMAND":
+			event.Command = strings.TrimPrefix(fields, "COMMAND=")
+			return
+		case key == "USER":
+			event.TargetUser = value
+		case key == "TTY":
+			event.TTY = value
+		case key == "PWD":
+			event.PWD = value
+		}
+		fields = rest
+	}
+}
@@ -0,0 +1,72 @@
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePrivilegeLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want *PrivilegeEvent
+	}{
+		{
+			"sudo command",
+			"Dec 12 14:00:00 web1 sudo:    alice : TTY=pts/0 ; PWD=/home/alice ; USER=root ; COMMAND=/usr/bin/apt update",
+			&PrivilegeEvent{Program: "sudo", User: "alice", TargetUser: "root", Result: PrivilegeSuccess, Command: "/usr/bin/apt update", TTY: "pts/0", PWD: "/home/alice"},
+		},
+		{
+			"sudo command with semicolons",
+			"Dec 12 14:00:00 web1 sudo: alice : TTY=pts/0 ; PWD=/ ; USER=root ; COMMAND=/bin/sh -c echo a ; echo b",
+			&PrivilegeEvent{Program: "sudo", User: "alice", TargetUser: "root", Result: PrivilegeSuccess, Command: "/bin/sh -c echo a ; echo b", TTY: "pts/0", PWD: "/"},
+		},
+		{
+			"sudo incorrect password",
+			"Dec 12 14:00:00 web1 sudo:    alice : 3 incorrect password attempts ; TTY=pts/0 ; PWD=/home/alice ; USER=root ; COMMAND=/bin/bash",
+			&PrivilegeEvent{Program: "sudo", User: "alice", TargetUser: "root", Result: PrivilegeFailed, Reason: "3 incorrect password attempts", Command: "/bin/bash", TTY: "pts/0", PWD: "/home/alice"},
+		},
+		{
+			"sudo not in sudoers",
+			"Dec 12 14:00:00 web1 sudo:      bob : user NOT in sudoers ; TTY=pts/1 ; PWD=/home/bob ; USER=root ; COMMAND=/bin/cat /etc/shadow",
+			&PrivilegeEvent{Program: "sudo", User: "bob", TargetUser: "root", Result: PrivilegeDenied, Reason: "user NOT in sudoers", Command: "/bin/cat /etc/shadow", TTY: "pts/1", PWD: "/home/bob"},
+		},
+		{
+			"PAM authentication failure",
+			"Dec 12 14:00:00 web1 su[4321]: pam_unix(su:auth): authentication failure; logname=alice uid=1000 euid=0 tty=/dev/pts/0 ruser=alice rhost=  user=root",
+			&PrivilegeEvent{Program: "su", User: "alice", TargetUser: "root", Result: PrivilegeAuthFailure, Reason: "authentication failure", TTY: "/dev/pts/0"},
+		},
+		{
+			"su session opened",
+			"Dec 12 14:00:00 web1 su[4322]: pam_unix(su-l:session): session opened for user root(uid=0) by alice(uid=1000)",
+			&PrivilegeEvent{Program: "su", User: "alice", TargetUser: "root", Result: PrivilegeSuccess},
+		},
+		{
+			"FAILED SU",
+			"Dec 12 14:00:00 web1 su[4323]: FAILED SU (to root) alice on pts/0",
+			&PrivilegeEvent{Program: "su", User: "alice", TargetUser: "root", Result: PrivilegeFailed, Reason: "authentication failure", TTY: "pts/0"},
+		},
+		{"sshd PAM failure", "Dec 12 14:00:00 web1 sshd[1300]: pam_unix(sshd:auth): authentication failure; logname= uid=0 euid=0 tty=ssh ruser= rhost=1.2.3.4  user=root", nil},
+		{"sudo session", "Dec 12 14:00:00 web1 sudo: pam_unix(sudo:session): session closed for user root", nil},
+		{"Without syslog header", "alice : TTY=pts/0 ; PWD=/ ; USER=root ; COMMAND=/bin/ls", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParsePrivilegeLine(tt.line)
+			if got == nil || tt.want == nil {
+				if got != tt.want {
+					t.Errorf("ParsePrivilegeLine() = %+v; want %+v", got, tt.want)
+				}
+				return
+			}
+			if got.Time.Month() != time.December {
+				t.Errorf("Time = %v", got.Time)
+			}
+			got.Time = time.Time{}
+			if *got != *tt.want {
+				t.Errorf("ParsePrivilegeLine() = %+v; want %+v", *got, *tt.want)
+			}
+		})
+	}
+}
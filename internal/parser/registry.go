@@ -0,0 +1,113 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// registry maps parser names (as used in config and by auto-discovery)
+// to constructors. Each call returns a fresh parser instance.
+var registry = map[string]func() LogParser{
+	"nginx":         func() LogParser { return &NginxParser{} },
+	"apache":        func() LogParser { return &ApacheParser{} },
+	"apache2":       func() LogParser { return &ApacheParser{} },
+	"httpd":         func() LogParser { return &ApacheParser{} },
+	"caddy":         func() LogParser { return &CaddyParser{} },
+	"tomcat":        func() LogParser { return &TomcatParser{} },
+	"traefik":       func() LogParser { return &TraefikParser{} },
+	"haproxy":       func() LogParser { return &HAProxyParser{} },
+	"envoy":         func() LogParser { return &EnvoyParser{} },
+	"lighttpd":      func() LogParser { return &LighttpdParser{} },
+	"system":        func() LogParser { return &SystemParser{} },
+	"alb":           func() LogParser { return &ALBParser{} },
+	"elb":           func() LogParser { return &ALBParser{} },
+	"cloudfront":    func() LogParser { return &CloudFrontParser{} },
+	"gcp_lb":        func() LogParser { return &GCPLoadBalancerParser{} },
+	"w3c":           func() LogParser { return NewW3CParser() },
+	"iis":           func() LogParser { return NewW3CParser() },
+	"ingress-nginx": func() LogParser { p, _ := NewIngressNginxParser(""); return p },
+	"logfmt":        func() LogParser { p, _ := NewLogfmtParser(LogfmtConfig{Name: "logfmt"}); return p },
+	"json":          func() LogParser { p, _ := NewJSONParser(JSONConfig{Name: "json", Fields: jsonDefaultFields}); return p },
+}
+
+// Auto is the parser name that detects the format (see AutoParser)
+const Auto = "auto"
+
+// New returns a new parser registered under name.
+func New(name string) (LogParser, error) {
+	if name == Auto {
+		return NewAutoParser(""), nil
+	}
+	ctor, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown parser %q (known: %v)", name, Names())
+	}
+	return ctor(), nil
+}
+
+// formats maps parser names to compilers for user-supplied log formats
+// (the nginx log_format string, ...).
+var formats = map[string]func(format string) (LogParser, error){
+	"nginx":         func(format string) (LogParser, error) { return NewNginxFormatParser(format) },
+	"apache":        func(format string) (LogParser, error) { return NewApacheFormatParser(format) },
+	"apache2":       func(format string) (LogParser, error) { return NewApacheFormatParser(format) },
+	"httpd":         func(format string) (LogParser, error) { return NewApacheFormatParser(format) },
+	"ingress-nginx": func(format string) (LogParser, error) { return NewIngressNginxParser(format) },
+}
+
+// formatFiles maps parser names to loaders that read a named format from
+// the server's own configuration file.
+var formatFiles = map[string]func(confPath, name string) (string, error){
+	"nginx":   LoadNginxFormat,
+	"apache":  LoadApacheFormat,
+	"apache2": LoadApacheFormat,
+	"httpd":   LoadApacheFormat,
+}
+
+// defaultServerConfig lists where each server's config usually lives; the
+// first existing file is read when a format is referenced by name only.
+var defaultServerConfig = map[string][]string{
+	"nginx":   {"/etc/nginx/nginx.conf"},
+	"apache":  {"/etc/apache2/apache2.conf", "/etc/httpd/conf/httpd.conf"},
+	"apache2": {"/etc/apache2/apache2.conf", "/etc/httpd/conf/httpd.conf"},
+	"httpd":   {"/etc/httpd/conf/httpd.conf", "/etc/apache2/apache2.conf"},
+}
+
+// NewFormat returns a parser generated from a custom log format string
+func NewFormat(name, format string) (LogParser, error) {
+	compile, ok := formats[name]
+	if !ok {
+		return nil, fmt.Errorf("parser %q does not support custom log formats", name)
+	}
+	return compile(format)
+}
+
+// LoadFormat reads the log format called formatName from the server config
+// file at confPath (or the parser's usual location if empty).
+func LoadFormat(name, confPath, formatName string) (string, error) {
+	load, ok := formatFiles[name]
+	if !ok {
+		return "", fmt.Errorf("parser %q does not support custom log formats", name)
+	}
+	if confPath == "" {
+		for _, p := range defaultServerConfig[name] {
+			confPath = p
+			if _, err := os.Stat(p); err == nil {
+				break
+			}
+		}
+	}
+	return load(confPath, formatName)
+}
+
+// Names returns the sorted list of registered parser names.
+func Names() []string {
+	names := make([]string, 0, len(registry)+1)
+	names = append(names, Auto)
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -2,7 +2,9 @@ package parser
 
 import (
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type SSHEventType int
@@ -10,7 +12,12 @@ type SSHEventType int
 const (
 	SSHLoginSuccess SSHEventType = iota
 	SSHLoginFailed
-	SSHDisconnect
+	SSHDisconnect       // "Disconnected from ..."
+	SSHInvalidUser      // "Invalid user admin from ..."
+	SSHConnectionClosed // "Connection closed/reset by ..." (usually preauth)
+	SSHMaxAuthAttempts  // "maximum authentication attempts exceeded for ..."
+	SSHSessionOpened    // pam_unix(sshd:session): session opened
+	SSHSessionClosed    // pam_unix(sshd:session): session closed
 	SSHUnknown
 )
 
@@ -18,58 +25,109 @@ type SSHLogEntry struct {
 	Type       SSHEventType
 	User       string
 	IP         string
+	Port       int
 	AuthMethod string
+
+	Time        time.Time // From the syslog header, zero if none
+	PID         int       // sshd process (one per connection), 0 if unknown
+	InvalidUser bool      // The user does not exist
+	Preauth     bool      // Logged before authentication completed ("[preauth]")
+	KeyType     string    // Public key logins, e.g. "ED25519" or "RSA-CERT"
+	Fingerprint string    // e.g. "SHA256:ohD8VZEXGWo6Ez8GSEJQ9WpafgLFsOfLOtGGQCQo6Og"
 }
 
 // Regex patterns for common SSH logs (OpenSSH)
 var (
 	// Accepted password for root from 192.168.1.1 port 22 ssh2
 	// Accepted publickey for user from 10.0.0.1 port 55555 ssh2: RSA SHA256:...
-	sshAcceptedRegex = regexp.MustCompile(`Accepted (\S+) for (\S+) from (\S+)`)
+	// Groups: 1 method, 2 user, 3 IP, 4 port, 5 key type, 6 fingerprint
+	sshAcceptedRegex = regexp.MustCompile(`Accepted (\S+) for (\S+) from (\S+)(?: port (\d+))?(?: [^\s:]+)?(?:: (\S+) (\S+))?`)
 
 	// Failed password for invalid user admin from 192.168.1.5 port 22 ssh2
 	// Failed password for root from 192.168.1.5 port 22 ssh2
-	sshFailedRegex = regexp.MustCompile(`Failed (\S+) for (?:invalid user )?(\S+) from (\S+)`)
+	// Groups: 1 method, 2 invalid, 3 user, 4 IP, 5 port, 6 key type, 7 fingerprint
+	sshFailedRegex = regexp.MustCompile(`Failed (\S+) for (invalid user )?(\S+) from (\S+)(?: port (\d+))?(?: [^\s:]+)?(?:: (\S+) (\S+))?`)
+
+	// Invalid user admin from 192.168.1.5 port 40000
+	sshInvalidUserRegex = regexp.MustCompile(`Invalid user (\S*) from (\S+)(?: port (\d+))?`)
+
+	// error: maximum authentication attempts exceeded for invalid user admin from 192.168.1.5 port 40000 ssh2 [preauth]
+	sshMaxAuthRegex = regexp.MustCompile(`maximum authentication attempts exceeded for (invalid user )?(\S+) from (\S+) port (\d+)`)
 
 	// Disconnected from user root 192.168.1.1 port 22
+	// Disconnected from authenticating user root 1.2.3.4 port 22 [preauth]
 	// Disconnected from 1.2.3.4 port 22
-	sshDisconnectRegex = regexp.MustCompile(`Disconnected from (?:user (\S+) )?(\S+)`)
+	// Groups: 1 kind of user, 2 user, 3 IP, 4 port
+	sshDisconnectRegex = regexp.MustCompile(`Disconnected from (?:(invalid user|authenticating user|user) (\S+) )?(\S+) port (\d+)`)
+
+	// Connection closed by authenticating user root 1.2.3.4 port 22 [preauth]
+	// Connection reset by 1.2.3.4 port 22 [preauth]
+	sshClosedRegex = regexp.MustCompile(`Connection (?:closed|reset) by (?:(invalid user|authenticating user|user) (\S+) )?(\S+) port (\d+)`)
+
+	// pam_unix(sshd:session): session opened for user alice(uid=1000) by (uid=0)
+	// pam_unix(sshd:session): session closed for user alice
+	sshSessionRegex = regexp.MustCompile(`pam_unix\(sshd:session\): session (opened|closed) for user ([^\s(]+)`)
 )
 
+// ParseSSHLine recognizes the sshd lines of an auth log. Returns nil for
+// other lines.
 func ParseSSHLine(line string) (*SSHLogEntry, error) {
+	header, _ := ParseSyslogHeader(line)
+	entry := &SSHLogEntry{Time: header.Time, Preauth: strings.HasSuffix(line, "[preauth]")}
+	if strings.HasPrefix(header.Program, "sshd") {
+		entry.PID = header.PID
+	}
+
 	// Check for Accepted (Success)
-	if matches := sshAcceptedRegex.FindStringSubmatch(line); matches != nil {
-		return &SSHLogEntry{
-			Type:       SSHLoginSuccess,
-			AuthMethod: matches[1],
-			User:       matches[2],
-			IP:         matches[3],
-		}, nil
+	if m := sshAcceptedRegex.FindStringSubmatch(line); m != nil {
+		entry.Type, entry.AuthMethod, entry.User, entry.IP = SSHLoginSuccess, m[1], m[2], m[3]
+		entry.Port, _ = strconv.Atoi(m[4])
+		entry.KeyType, entry.Fingerprint = m[5], m[6]
+		return entry, nil
 	}
 
 	// Check for Failed
-	if matches := sshFailedRegex.FindStringSubmatch(line); matches != nil {
-		return &SSHLogEntry{
-			Type:       SSHLoginFailed,
-			AuthMethod: matches[1],
-			User:       matches[2],
-			IP:         matches[3],
-		}, nil
+	if m := sshFailedRegex.FindStringSubmatch(line); m != nil {
+		entry.Type, entry.AuthMethod, entry.InvalidUser, entry.User, entry.IP = SSHLoginFailed, m[1], m[2] != "", m[3], m[4]
+		entry.Port, _ = strconv.Atoi(m[5])
+		entry.KeyType, entry.Fingerprint = m[6], m[7]
+		return entry, nil
+	}
+
+	if m := sshInvalidUserRegex.FindStringSubmatch(line); m != nil {
+		entry.Type, entry.InvalidUser, entry.User, entry.IP = SSHInvalidUser, true, m[1], m[2]
+		entry.Port, _ = strconv.Atoi(m[3])
+		return entry, nil
+	}
+
+	if m := sshMaxAuthRegex.FindStringSubmatch(line); m != nil {
+		entry.Type, entry.InvalidUser, entry.User, entry.IP = SSHMaxAuthAttempts, m[1] != "", m[2], m[3]
+		entry.Port, _ = strconv.Atoi(m[4])
+		return entry, nil
+	}
+
+	// Disconnects end a session, unless before authentication
+	for _, d := range []struct {
+		re  *regexp.Regexp
+		typ SSHEventType
+	}{{sshDisconnectRegex, SSHDisconnect}, {sshClosedRegex, SSHConnectionClosed}} {
+		if m := d.re.FindStringSubmatch(line); m != nil {
+			entry.Type, entry.User, entry.IP = d.typ, m[2], m[3]
+			entry.Port, _ = strconv.Atoi(m[4])
+			entry.InvalidUser = m[1] == "invalid user"
+			if m[1] == "invalid user" || m[1] == "authenticating user" {
+				entry.Preauth = true
+			}
+			return entry, nil
+		}
 	}
 
-	// Check for Disconnect (often indicates session end)
-	// Note: Disconnect logs vary significantly, this is a best effort
-	if strings.Contains(line, "sshd") && strings.Contains(line, "Disconnected from") {
-		// Try regex
-		// if matches := sshDisconnectRegex.FindStringSubmatch(line); matches != nil {
-		// 	user := ""
-		// 	// if capture group 1 is empty, it might just be "Disconnected from IP"
-		// 	// This part is tricky because "Disconnected from 1.2.3.4" doesn't give us the user unless we track state or look at earlier logs.
-		// 	// For now we might just count disconnects globally or by IP.
-		//  return &SSHLogEntry{...}
-		// }
-		// Simplified return for disconnect
-		return &SSHLogEntry{Type: SSHDisconnect}, nil
+	if m := sshSessionRegex.FindStringSubmatch(line); m != nil {
+		entry.Type, entry.User = SSHSessionOpened, m[2]
+		if m[1] == "closed" {
+			entry.Type = SSHSessionClosed
+		}
+		return entry, nil
 	}
 
 	return nil, nil // Not a relevant line
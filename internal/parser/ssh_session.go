@@ -0,0 +1,130 @@
+package parser
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SSHSession is an SSH login that has not ended yet
+type SSHSession struct {
+	User  string
+	IP    string
+	Port  int
+	Start time.Time
+
+	scope string
+}
+
+// SSHSessions tracks SSH sessions from login to logout. The login and the
+// PAM session lines come from the same sshd process, so sessions are keyed
+// by its PID (or by client address and port when lines carry no PID); the
+// unprivileged child logging "Disconnected from user" is matched by client
+// address and port. Ends whose start was not seen are ignored, so the
+// count never drifts below zero; sessions whose end is never seen are
+// dropped once older than maxAge (see Expire).
+type SSHSessions struct {
+	mu       sync.Mutex
+	sessions map[string]SSHSession
+	maxAge   time.Duration
+}
+
+func NewSSHSessions(maxAge time.Duration) *SSHSessions {
+	return &SSHSessions{sessions: make(map[string]SSHSession), maxAge: maxAge}
+}
+
+// SetMaxAge changes how long a session is tracked without its end being seen
+func (s *SSHSessions) SetMaxAge(maxAge time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxAge = maxAge
+}
+
+// sessionKey identifies e's connection within scope (e.g. the service);
+// "" if it cannot be identified
+func sessionKey(scope string, e *SSHLogEntry) string {
+	if e.PID != 0 {
+		return scope + "|" + strconv.Itoa(e.PID)
+	}
+	if e.IP != "" && e.Port != 0 {
+		return scope + "|" + e.IP + ":" + strconv.Itoa(e.Port)
+	}
+	return ""
+}
+
+// Start records the session of a successful login or opened PAM session.
+// The PAM line of a tracked login is part of the same session. Any other
+// start under the key of a tracked session (a reused PID) replaces it, as
+// its end was missed; the replaced session is returned with true.
+func (s *SSHSessions) Start(scope string, e *SSHLogEntry) (SSHSession, bool) {
+	key := sessionKey(scope, e)
+	if key == "" {
+		return SSHSession{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, replaced := s.sessions[key]
+	if replaced && e.Type == SSHSessionOpened && old.User == e.User {
+		return SSHSession{}, false
+	}
+	start := e.Time
+	if start.IsZero() {
+		start = time.Now()
+	}
+	s.sessions[key] = SSHSession{User: e.User, IP: e.IP, Port: e.Port, Start: start, scope: scope}
+	return old, replaced
+}
+
+// Expire removes and returns the sessions started more than the max age
+// before now, whose end was most likely not logged
+func (s *SSHSessions) Expire(now time.Time) []SSHSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var expired []SSHSession
+	for key, session := range s.sessions {
+		if now.Sub(session.Start) > s.maxAge {
+			expired = append(expired, session)
+			delete(s.sessions, key)
+		}
+	}
+	return expired
+}
+
+// End removes the session closed by e and returns it with its duration.
+// Returns false if the session was not tracked (already ended, or started
+// before the log was read).
+func (s *SSHSessions) End(scope string, e *SSHLogEntry) (SSHSession, time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := sessionKey(scope, e)
+	session, ok := s.sessions[key]
+	if !ok && e.IP != "" && e.Port != 0 {
+		for k, candidate := range s.sessions {
+			if candidate.scope == scope && candidate.IP == e.IP && candidate.Port == e.Port {
+				key, session, ok = k, candidate, true
+				break
+			}
+		}
+	}
+	if !ok {
+		return SSHSession{}, 0, false
+	}
+	delete(s.sessions, key)
+
+	end := e.Time
+	if end.IsZero() {
+		end = time.Now()
+	}
+	d := end.Sub(session.Start)
+	if d < 0 {
+		d = 0
+	}
+	return session, d, true
+}
+
+// Active returns the number of open sessions
+func (s *SSHSessions) Active() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sessions)
+}
@@ -0,0 +1,141 @@
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSSHLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want *SSHLogEntry
+	}{
+		{
+			"Accepted publickey",
+			"Dec 12 14:00:00 web1 sshd[1234]: Accepted publickey for alice from 10.0.0.1 port 55555 ssh2: ED25519 SHA256:ohD8VZEXGWo6Ez8GSEJQ9WpafgLFsOfLOtGGQCQo6Og",
+			&SSHLogEntry{Type: SSHLoginSuccess, User: "alice", IP: "10.0.0.1", Port: 55555, AuthMethod: "publickey", PID: 1234, KeyType: "ED25519", Fingerprint: "SHA256:ohD8VZEXGWo6Ez8GSEJQ9WpafgLFsOfLOtGGQCQo6Og"},
+		},
+		{
+			"Failed password, invalid user",
+			"Dec 12 14:00:00 web1 sshd[1300]: Failed password for invalid user admin from 1.2.3.4 port 40000 ssh2",
+			&SSHLogEntry{Type: SSHLoginFailed, User: "admin", IP: "1.2.3.4", Port: 40000, AuthMethod: "password", PID: 1300, InvalidUser: true},
+		},
+		{
+			"Invalid user",
+			"Dec 12 14:00:00 web1 sshd[1300]: Invalid user admin from 1.2.3.4 port 40000",
+			&SSHLogEntry{Type: SSHInvalidUser, User: "admin", IP: "1.2.3.4", Port: 40000, PID: 1300, InvalidUser: true},
+		},
+		{
+			"Max auth attempts",
+			"Dec 12 14:00:00 web1 sshd[1301]: error: maximum authentication attempts exceeded for root from 1.2.3.4 port 40001 ssh2 [preauth]",
+			&SSHLogEntry{Type: SSHMaxAuthAttempts, User: "root", IP: "1.2.3.4", Port: 40001, PID: 1301, Preauth: true},
+		},
+		{
+			"Preauth disconnect",
+			"Dec 12 14:00:00 web1 sshd[1300]: Disconnected from invalid user admin 1.2.3.4 port 40000 [preauth]",
+			&SSHLogEntry{Type: SSHDisconnect, User: "admin", IP: "1.2.3.4", Port: 40000, PID: 1300, InvalidUser: true, Preauth: true},
+		},
+		{
+			"Connection closed by authenticating user",
+			"Dec 12 14:00:00 web1 sshd[1302]: Connection closed by authenticating user root 1.2.3.4 port 40002 [preauth]",
+			&SSHLogEntry{Type: SSHConnectionClosed, User: "root", IP: "1.2.3.4", Port: 40002, PID: 1302, Preauth: true},
+		},
+		{
+			"Logout",
+			"Dec 12 14:00:00 web1 sshd[1240]: Disconnected from user alice 10.0.0.1 port 55555",
+			&SSHLogEntry{Type: SSHDisconnect, User: "alice", IP: "10.0.0.1", Port: 55555, PID: 1240},
+		},
+		{
+			"PAM session opened",
+			"Dec 12 14:00:00 web1 sshd[1234]: pam_unix(sshd:session): session opened for user alice(uid=1000) by (uid=0)",
+			&SSHLogEntry{Type: SSHSessionOpened, User: "alice", PID: 1234},
+		},
+		{
+			"Without syslog header",
+			"Accepted password for root from 192.168.1.1 port 22 ssh2",
+			&SSHLogEntry{Type: SSHLoginSuccess, User: "root", IP: "192.168.1.1", Port: 22, AuthMethod: "password"},
+		},
+		{"Other line", "Dec 12 14:00:00 web1 sshd[1]: Server listening on 0.0.0.0 port 22.", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSSHLine(tt.line)
+			if err != nil {
+				t.Fatalf("ParseSSHLine() error = %v", err)
+			}
+			if got == nil || tt.want == nil {
+				if got != tt.want {
+					t.Errorf("ParseSSHLine() = %+v; want %+v", got, tt.want)
+				}
+				return
+			}
+			if tt.want.PID != 0 && got.Time.Month() != time.December {
+				t.Errorf("Time = %v", got.Time)
+			}
+			got.Time = time.Time{}
+			if *got != *tt.want {
+				t.Errorf("ParseSSHLine() = %+v; want %+v", *got, *tt.want)
+			}
+		})
+	}
+}
+
+func TestSSHSessions(t *testing.T) {
+	s := NewSSHSessions(24 * time.Hour)
+	start := time.Date(2023, 12, 12, 14, 0, 0, 0, time.UTC)
+	login := &SSHLogEntry{Type: SSHLoginSuccess, User: "alice", IP: "10.0.0.1", Port: 55555, PID: 1234, Time: start}
+	pam := &SSHLogEntry{Type: SSHSessionOpened, User: "alice", PID: 1234, Time: start}
+
+	s.Start("ssh", login)
+	if _, replaced := s.Start("ssh", pam); replaced || s.Active() != 1 {
+		t.Fatal("Start() should track the login once")
+	}
+	if s.Start("other-host", login); s.Active() != 2 {
+		t.Fatalf("Active() = %d; want 2 (sessions are per scope)", s.Active())
+	}
+
+	// Logged by the unprivileged child, matched by client address
+	logout := &SSHLogEntry{Type: SSHDisconnect, User: "alice", IP: "10.0.0.1", Port: 55555, PID: 1240, Time: start.Add(90 * time.Second)}
+	session, d, ok := s.End("ssh", logout)
+	if !ok || session.User != "alice" || d != 90*time.Second {
+		t.Errorf("End() = %+v, %v, %v; want alice, 1m30s, true", session, d, ok)
+	}
+	// PAM closes the same session afterwards; ends without a start are ignored
+	if _, _, ok := s.End("ssh", &SSHLogEntry{Type: SSHSessionClosed, User: "alice", PID: 1234}); ok {
+		t.Error("End() of an ended session = true")
+	}
+	if s.Active() != 1 {
+		t.Errorf("Active() = %d; want 1", s.Active())
+	}
+}
+
+func TestSSHSessionsLost(t *testing.T) {
+	start := time.Date(2023, 12, 12, 14, 0, 0, 0, time.UTC)
+	login := func(user string, pid int, at time.Duration) *SSHLogEntry {
+		return &SSHLogEntry{Type: SSHLoginSuccess, User: user, IP: "10.0.0.1", Port: 50000 + pid, PID: pid, Time: start.Add(at)}
+	}
+
+	// sshd reused the PID of a session whose end was not logged
+	s := NewSSHSessions(time.Hour)
+	s.Start("ssh", login("alice", 1234, 0))
+	old, replaced := s.Start("ssh", login("bob", 1234, time.Minute))
+	if !replaced || old.User != "alice" || s.Active() != 1 {
+		t.Errorf("Start() = %+v, %v with %d active; want alice replaced, 1 active", old, replaced, s.Active())
+	}
+	if session, _, ok := s.End("ssh", &SSHLogEntry{Type: SSHSessionClosed, PID: 1234, Time: start.Add(2 * time.Minute)}); !ok || session.User != "bob" {
+		t.Errorf("End() = %+v, %v; want bob's session", session, ok)
+	}
+
+	// Sessions older than the max age are dropped
+	s.Start("ssh", login("alice", 1, 0))
+	s.Start("ssh", login("bob", 2, 30*time.Minute))
+	if expired := s.Expire(start.Add(time.Hour)); len(expired) != 0 {
+		t.Errorf("Expire() at max age = %+v; want none", expired)
+	}
+	expired := s.Expire(start.Add(61 * time.Minute))
+	if len(expired) != 1 || expired[0].User != "alice" || s.Active() != 1 {
+		t.Errorf("Expire() = %+v with %d active; want alice, 1 active", expired, s.Active())
+	}
+}
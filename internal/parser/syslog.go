@@ -0,0 +1,49 @@
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// SyslogHeader is the prefix of a syslog text line (auth.log, secure,
+// maillog, ...)
+type SyslogHeader struct {
+	Time    time.Time
+	Host    string
+	Program string // e.g. "sshd", "postfix/smtpd"
+	PID     int    // 0 if not logged
+	Message string
+}
+
+// Dec 12 14:00:00 host sshd[1234]: message
+// 2023-12-12T14:00:00.123456+00:00 host sshd[1234]: message (RFC 3339, rsyslog high precision)
+// Groups: 1 RFC 3339 time, 2 BSD time, 3 host, 4 program, 5 PID, 6 message
+var syslogHeaderRegex = regexp.MustCompile(`^(?:(\d{4}-\d{2}-\d{2}T\S+)|([A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2}))\s+(\S+)\s+([^\s\[:]+)(?:\[(\d+)\])?:\s?(.*)$`)
+
+// ParseSyslogHeader splits a syslog line into its header and message.
+// BSD timestamps have no year: the current one is assumed, or the previous
+// one if that would put the line in the future (logs read after New Year).
+func ParseSyslogHeader(line string) (SyslogHeader, bool) {
+	m := syslogHeaderRegex.FindStringSubmatch(line)
+	if m == nil {
+		return SyslogHeader{Message: line}, false
+	}
+	h := SyslogHeader{Host: m[3], Program: m[4], Message: m[6]}
+	h.PID, _ = strconv.Atoi(m[5])
+	if m[1] != "" {
+		h.Time, _ = time.Parse(time.RFC3339Nano, m[1])
+		return h, true
+	}
+
+	now := time.Now()
+	t, err := time.ParseInLocation("Jan _2 15:04:05", m[2], time.Local)
+	if err == nil {
+		t = t.AddDate(now.Year(), 0, 0)
+		if t.After(now.Add(24 * time.Hour)) {
+			t = t.AddDate(-1, 0, 0)
+		}
+		h.Time = t
+	}
+	return h, true
+}
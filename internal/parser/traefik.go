@@ -3,6 +3,9 @@ package parser
 import (
 	"encoding/json"
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -11,28 +14,63 @@ type TraefikParser struct{}
 // TraefikJSONEntry represents a Traefik access log line in JSON format
 // Fields based on common Traefik access log structure
 type TraefikJSONEntry struct {
-	ClientHost            string            `json:"ClientHost"`
-	ClientUsername        string            `json:"ClientUsername"`
-	StartUTC              string            `json:"StartUTC"` // 2023-12-01T12:00:00Z
-	RequestMethod         string            `json:"RequestMethod"`
-	RequestPath           string            `json:"RequestPath"`
-	RequestProtocol       string            `json:"RequestProtocol"`
-	DownstreamStatus      int               `json:"DownstreamStatus"`
-	DownstreamContentSize int               `json:"DownstreamContentSize"`
-	// Headers might be flattened or in a map depending on config
-	// Usually Traefik log doesn't include headers by default unless configured
-	// We check for some common flattened keys if they exist in a dynamic map
-	RequestHeaders map[string]interface{} `json:"-"` 
+	ClientHost            string `json:"ClientHost"`
+	ClientUsername        string `json:"ClientUsername"`
+	StartUTC              string `json:"StartUTC"` // 2023-12-01T12:00:00Z
+	RequestMethod         string `json:"RequestMethod"`
+	RequestPath           string `json:"RequestPath"`
+	RequestProtocol       string `json:"RequestProtocol"`
+	RequestScheme         string `json:"RequestScheme"`
+	DownstreamStatus      int    `json:"DownstreamStatus"`
+	DownstreamContentSize int    `json:"DownstreamContentSize"`
+	RequestHost           string `json:"RequestHost"`
+	RequestContentSize    int    `json:"RequestContentSize"`
+	Duration              int64  `json:"Duration"`       // Nanoseconds
+	OriginDuration        int64  `json:"OriginDuration"` // Nanoseconds spent on the backend
+	OriginStatus          int    `json:"OriginStatus"`   // Status returned by the backend
+	ServiceAddr           string `json:"ServiceAddr"`
+	ServiceURL            string `json:"ServiceURL"`
+	RouterName            string `json:"RouterName"`  // e.g. "api@docker"
+	ServiceName           string `json:"ServiceName"` // Traefik service (backend), e.g. "api-svc@docker"
+	EntryPointName        string `json:"entryPointName"`
+	RetryAttempts         int    `json:"RetryAttempts"`
+	TLSVersion            string `json:"TLSVersion"`
+	TLSCipher             string `json:"TLSCipher"`
+}
+
+// Traefik's default (Common Log Format based) text access log
+// 10.0.0.1 - bob [12/Dec/2023:14:00:00 +0000] "GET /api HTTP/1.1" 200 512 "-" "curl/8.0" 42 "api@docker" "http://10.1.0.5:8080" 12ms
+// Groups: 1 client, 2 user, 3 time, 4-6 request, 7 status, 8 size, 9 referer, 10 UA,
+// 11 request count, 12 router, 13 server URL, 14 duration (ms)
+var traefikCLFRegex = regexp.MustCompile(`^(\S+) - (\S+) \[([^\]]+)\] "(\S+) (\S+) (\S+)" (\d+) (\d+|-) "([^"]*)" "([^"]*)" (\d+) "([^"]*)" "([^"]*)" (\d+)ms`)
+
+// Header fields are flattened with a prefix when enabled in the access log
+// config (accessLog.fields.headers). They are kept under nginx-style names.
+var traefikHeaderPrefixes = []struct{ prefix, extra string }{
+	{"request_", "http_"},
+	{"downstream_", "sent_http_"},
+	{"origin_", "upstream_http_"},
 }
 
 func (p *TraefikParser) Parse(line string) (*GenericLogEntry, error) {
+	if !strings.HasPrefix(line, "{") {
+		return p.parseCLF(line)
+	}
+
 	var entry TraefikJSONEntry
-	
 	// We unmarshal into the struct for known fields
 	if err := json.Unmarshal([]byte(line), &entry); err != nil {
 		return nil, fmt.Errorf("failed to parse traefik json: %v", err)
 	}
-	
+	if entry.RequestMethod == "" && entry.DownstreamStatus == 0 {
+		return nil, fmt.Errorf("not a traefik access log line: %s", line)
+	}
+	// ...and into a map for the flattened headers
+	var raw map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse traefik json: %v", err)
+	}
+
 	// Timestamp
 	t, err := time.Parse(time.RFC3339Nano, entry.StartUTC)
 	if err != nil {
@@ -43,15 +81,7 @@ func (p *TraefikParser) Parse(line string) (*GenericLogEntry, error) {
 		}
 	}
 
-	// Try to extract Referer/UA from the raw map if needed
-	// For now, simpler implementation assuming default JSON access log without headers
-	referer := ""
-	userAgent := ""
-	
-	// Note: Traefik often puts headers in 'request_Referer' field key if "buffering" middleware or specific config used
-	// We leave them empty for default config to avoid complex map parsing overhead unless requested
-
-	return &GenericLogEntry{
+	result := &GenericLogEntry{
 		Service:       "traefik",
 		RemoteIP:      entry.ClientHost,
 		RemoteUser:    entry.ClientUsername,
@@ -61,7 +91,101 @@ func (p *TraefikParser) Parse(line string) (*GenericLogEntry, error) {
 		Protocol:      entry.RequestProtocol,
 		Status:        entry.DownstreamStatus,
 		BodyBytesSent: entry.DownstreamContentSize,
-		Referer:       referer,
-		UserAgent:     userAgent,
-	}, nil
+		Latency:       float64(entry.Duration) / 1e9,
+
+		Host:            entry.RequestHost,
+		RequestBytes:    entry.RequestContentSize,
+		UpstreamAddr:    entry.ServiceAddr,
+		UpstreamLatency: float64(entry.OriginDuration) / 1e9,
+		TLSProtocol:     entry.TLSVersion,
+		TLSCipher:       entry.TLSCipher,
+	}
+
+	for key, v := range raw {
+		for _, h := range traefikHeaderPrefixes {
+			name, ok := strings.CutPrefix(key, h.prefix)
+			if !ok || name == "" {
+				continue
+			}
+			value := jsonString(v)
+			if h.prefix == "request_" && setTraefikRequestHeader(result, name, value) {
+				continue
+			}
+			result.SetExtra(h.extra+headerVar(name), value)
+		}
+	}
+
+	for key, value := range map[string]string{
+		"router_name":  entry.RouterName,
+		"service_name": entry.ServiceName,
+		"service_url":  entry.ServiceURL,
+		"entry_point":  entry.EntryPointName,
+		"scheme":       entry.RequestScheme,
+	} {
+		if value != "" {
+			result.SetExtra(key, value)
+		}
+	}
+	if entry.OriginStatus != 0 {
+		result.SetExtra("upstream_status", strconv.Itoa(entry.OriginStatus))
+	}
+	if entry.RetryAttempts != 0 {
+		result.SetExtra("retry_attempts", strconv.Itoa(entry.RetryAttempts))
+	}
+	return result, nil
+}
+
+// setTraefikRequestHeader maps the request headers that have a dedicated
+// field; returns false for the others
+func setTraefikRequestHeader(entry *GenericLogEntry, name, value string) bool {
+	switch strings.ToLower(name) {
+	case "user-agent":
+		entry.UserAgent = value
+	case "referer":
+		entry.Referer = value
+	case "x-forwarded-for":
+		entry.ForwardedFor = splitForwardedFor(value)
+	case "x-request-id":
+		entry.RequestID = value
+	default:
+		return false
+	}
+	return true
+}
+
+func (p *TraefikParser) parseCLF(line string) (*GenericLogEntry, error) {
+	m := traefikCLFRegex.FindStringSubmatch(line)
+	if m == nil {
+		return nil, fmt.Errorf("failed to parse traefik line: %s", line)
+	}
+
+	t, err := time.Parse("02/Jan/2006:15:04:05 -0700", m[3])
+	if err != nil {
+		t = time.Now()
+	}
+	status, _ := strconv.Atoi(m[7])
+	size, _ := strconv.Atoi(m[8])
+	ms, _ := strconv.Atoi(m[14])
+
+	entry := &GenericLogEntry{
+		Service:       "traefik",
+		RemoteIP:      m[1],
+		RemoteUser:    m[2],
+		TimeLocal:     t,
+		Method:        m[4],
+		Path:          m[5],
+		Protocol:      m[6],
+		Status:        status,
+		BodyBytesSent: size,
+		Referer:       dash(m[9]),
+		UserAgent:     dash(m[10]),
+		Latency:       float64(ms) / 1000,
+	}
+	if router := dash(m[12]); router != "" {
+		entry.SetExtra("router_name", router)
+	}
+	if url := dash(m[13]); url != "" {
+		entry.SetExtra("service_url", url)
+	}
+	return entry, nil
 }
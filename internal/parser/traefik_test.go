@@ -0,0 +1,56 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTraefikParser(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		want  GenericLogEntry
+		extra map[string]string
+		time  time.Time
+	}{
+		{
+			"JSON with headers",
+			`{"ClientHost":"10.0.0.1","ClientUsername":"-","StartUTC":"2023-12-12T14:00:00.123456789Z","RequestMethod":"GET","RequestPath":"/api","RequestProtocol":"HTTP/2.0","RequestScheme":"https","RequestHost":"api.example.com","DownstreamStatus":502,"DownstreamContentSize":11,"Duration":25000000,"OriginDuration":20000000,"OriginStatus":502,"RouterName":"api@docker","ServiceName":"api-svc@docker","ServiceAddr":"10.1.0.5:8080","ServiceURL":"http://10.1.0.5:8080","entryPointName":"websecure","RetryAttempts":1,"request_User-Agent":"sqlmap/1.7","request_Referer":"https://example.com/","request_X-Forwarded-For":"1.2.3.4","request_X-Api-Key":"k","downstream_Content-Type":"text/plain"}`,
+			GenericLogEntry{RemoteIP: "10.0.0.1", RemoteUser: "-", Method: "GET", Path: "/api", Protocol: "HTTP/2.0", Status: 502, BodyBytesSent: 11, Referer: "https://example.com/", UserAgent: "sqlmap/1.7",
+				Latency: 0.025, Host: "api.example.com", ForwardedFor: []string{"1.2.3.4"}, UpstreamAddr: "10.1.0.5:8080", UpstreamLatency: 0.02},
+			map[string]string{
+				"router_name": "api@docker", "service_name": "api-svc@docker", "service_url": "http://10.1.0.5:8080", "entry_point": "websecure", "scheme": "https",
+				"upstream_status": "502", "retry_attempts": "1", "http_x_api_key": "k", "sent_http_content_type": "text/plain",
+			},
+			time.Date(2023, 12, 12, 14, 0, 0, 123456789, time.UTC),
+		},
+		{
+			"Common log format",
+			`10.0.0.2 - - [12/Dec/2023:14:00:00 +0000] "POST /login HTTP/1.1" 401 12 "-" "curl/8.0" 42 "auth@file" "http://10.1.0.6:80" 7ms`,
+			GenericLogEntry{RemoteIP: "10.0.0.2", RemoteUser: "-", Method: "POST", Path: "/login", Protocol: "HTTP/1.1", Status: 401, BodyBytesSent: 12, UserAgent: "curl/8.0", Latency: 0.007},
+			map[string]string{"router_name": "auth@file", "service_url": "http://10.1.0.6:80"},
+			time.Date(2023, 12, 12, 14, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := (&TraefikParser{}).Parse(tt.line)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !got.TimeLocal.Equal(tt.time) {
+				t.Errorf("TimeLocal = %v; want %v", got.TimeLocal, tt.time)
+			}
+			extra := got.Extra
+			got.TimeLocal, got.Service, got.Extra = time.Time{}, "", nil
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Parse() = %+v; want %+v", *got, tt.want)
+			}
+			if !reflect.DeepEqual(map[string]string(extra), tt.extra) {
+				t.Errorf("Parse() extra = %v; want %v", extra, tt.extra)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package parser
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StatefulParser is implemented by parsers whose lines depend on earlier
+// lines of the same file. Next is called for every line in file order,
+// before lines are parsed concurrently, and returns the parser to use for
+// that line, or nil if the line only updates the state. Header reports the
+// lines that update the state, so they can be read again when a file is
+// resumed mid-way.
+type StatefulParser interface {
+	LogParser
+	Next(line string) LogParser
+	Header(line string) bool
+}
+
+// W3CParser reads W3C extended log files (IIS, some CDNs). Columns are
+// named by the last "#Fields:" directive, which may change mid-file; until
+// one is seen the IIS default fields are assumed. Use one W3CParser per file.
+type W3CParser struct {
+	mu     sync.Mutex
+	fields *w3cFields
+}
+
+// w3cFields parses data lines with one "#Fields:" directive. It is never
+// modified, so lines already handed out keep their columns.
+type w3cFields struct {
+	names []string
+	date  string // From "#Date:", for files without a date column
+}
+
+// w3cDefaultFields are IIS's default W3C fields
+var w3cDefaultFields = strings.Fields("date time s-ip cs-method cs-uri-stem cs-uri-query s-port cs-username c-ip cs(User-Agent) cs(Referer) sc-status sc-substatus sc-win32-status time-taken")
+
+// w3cExtras maps standard fields without a dedicated entry field to Extra keys
+var w3cExtras = map[string]string{
+	"s-ip":            "server_addr",
+	"s-port":          "server_port",
+	"s-sitename":      "site_name",
+	"s-computername":  "server_name",
+	"sc-substatus":    "substatus",
+	"sc-win32-status": "win32_status",
+	"x-edge-location": "edge_location",
+}
+
+func NewW3CParser() *W3CParser {
+	return &W3CParser{fields: &w3cFields{names: w3cDefaultFields}}
+}
+
+// Next implements StatefulParser
+func (p *W3CParser) Next(line string) LogParser {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if strings.HasPrefix(line, "#") {
+		p.directive(line)
+		return nil
+	}
+	return p.fields
+}
+
+// Header implements StatefulParser: directives start with "#"
+func (p *W3CParser) Header(line string) bool {
+	return strings.HasPrefix(line, "#")
+}
+
+// Parse implements LogParser, for lines read in order. Directives update
+// the fields and are reported as errors.
+func (p *W3CParser) Parse(line string) (*GenericLogEntry, error) {
+	lp := p.Next(line)
+	if lp == nil {
+		return nil, fmt.Errorf("w3c directive: %s", line)
+	}
+	return lp.Parse(line)
+}
+
+// directive applies "#Fields:" and "#Date:"; others (#Software, #Version,
+// #Remark, ...) are ignored. Called with mu held.
+func (p *W3CParser) directive(line string) {
+	name, value, ok := strings.Cut(line[1:], ":")
+	if !ok {
+		return
+	}
+	value = strings.TrimSpace(value)
+	switch strings.ToLower(name) {
+	case "fields":
+		if names := strings.Fields(value); len(names) > 0 {
+			p.fields = &w3cFields{names: names, date: p.fields.date}
+		}
+	case "date":
+		date, _, _ := strings.Cut(value, " ")
+		p.fields = &w3cFields{names: p.fields.names, date: date}
+	}
+}
+
+// Parse implements LogParser for one data line
+func (f *w3cFields) Parse(line string) (*GenericLogEntry, error) {
+	// Space separated (IIS writes spaces in values as "+"), or tab
+	// separated (values are URL encoded)
+	sep, decode := " ", func(v string) string { return strings.ReplaceAll(v, "+", " ") }
+	if strings.Contains(line, "\t") {
+		sep, decode = "\t", w3cUnescape
+	}
+	values := strings.Split(line, sep)
+	if len(values) != len(f.names) {
+		return nil, fmt.Errorf("failed to parse w3c line (%d fields, want %d): %s", len(values), len(f.names), line)
+	}
+
+	entry := &GenericLogEntry{Service: "w3c", RemoteUser: "-"}
+	date, clock, query := f.date, "", ""
+	for i, name := range f.names {
+		value := dash(values[i])
+		if value == "" {
+			continue
+		}
+		switch name {
+		case "date":
+			date = value
+		case "time":
+			clock = value
+		case "c-ip":
+			entry.RemoteIP = value
+		case "cs-username":
+			entry.RemoteUser = value
+		case "cs-method":
+			entry.Method = value
+		case "cs-uri-stem":
+			entry.Path = value
+		case "cs-uri-query":
+			query = value
+		case "cs-uri":
+			entry.Path = value
+		case "cs-version", "cs-protocol-version":
+			entry.Protocol = value
+		case "sc-status":
+			entry.Status, _ = strconv.Atoi(value)
+		case "sc-bytes":
+			entry.BodyBytesSent, _ = strconv.Atoi(value)
+		case "cs-bytes":
+			entry.RequestBytes, _ = strconv.Atoi(value)
+		case "time-taken":
+			entry.Latency = w3cTimeTaken(value)
+		case "cs-host", "cs(Host)", "x-host-header":
+			entry.Host = value
+		case "cs(User-Agent)":
+			entry.UserAgent = decode(value)
+		case "cs(Referer)":
+			entry.Referer = decode(value)
+		case "x-forwarded-for", "cs(X-Forwarded-For)":
+			entry.ForwardedFor = splitForwardedFor(decode(value))
+		case "ssl-protocol":
+			entry.TLSProtocol = value
+		case "ssl-cipher":
+			entry.TLSCipher = value
+		default:
+			f.extra(entry, name, decode(value))
+		}
+	}
+	if entry.Method == "" && entry.Status == 0 {
+		return nil, fmt.Errorf("not a w3c access log line: %s", line)
+	}
+	if query != "" {
+		entry.Path += "?" + query
+	}
+
+	// Times are UTC
+	if t, err := time.Parse("2006-01-02 15:04:05", date+" "+clock); err == nil {
+		entry.TimeLocal = t
+	} else {
+		entry.TimeLocal = time.Now()
+	}
+	return entry, nil
+}
+
+// extra keeps other fields: request/response headers under nginx-style
+// names (cs(Cookie) -> http_cookie, sc(Content-Type) -> sent_http_content_type),
+// the rest with "-" replaced by "_"
+func (f *w3cFields) extra(entry *GenericLogEntry, name, value string) {
+	if key, ok := w3cExtras[name]; ok {
+		entry.SetExtra(key, value)
+		return
+	}
+	for _, h := range []struct{ prefix, extra string }{{"cs(", "http_"}, {"sc(", "sent_http_"}} {
+		if header, ok := strings.CutPrefix(name, h.prefix); ok && strings.HasSuffix(header, ")") {
+			entry.SetExtra(h.extra+headerVar(strings.TrimSuffix(header, ")")), value)
+			return
+		}
+	}
+	entry.SetExtra(strings.ReplaceAll(name, "-", "_"), value)
+}
+
+// w3cTimeTaken converts time-taken to seconds: IIS logs whole milliseconds,
+// CDNs (CloudFront, ...) fractional seconds
+func w3cTimeTaken(value string) float64 {
+	if strings.Contains(value, ".") {
+		v, _ := strconv.ParseFloat(value, 64)
+		return v
+	}
+	ms, _ := strconv.Atoi(value)
+	return float64(ms) / 1000
+}
+
+// w3cUnescape decodes the URL encoding of tab separated W3C values
+// (CloudFront logs spaces as "%20"); the value is kept as is if invalid
+func w3cUnescape(value string) string {
+	if v, err := url.PathUnescape(value); err == nil {
+		return v
+	}
+	return value
+}
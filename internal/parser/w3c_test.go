@@ -0,0 +1,59 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestW3CParser(t *testing.T) {
+	p := NewW3CParser()
+	lines := []string{
+		"#Software: Microsoft Internet Information Services 10.0",
+		"#Version: 1.0",
+		"#Date: 2023-12-12 14:00:00",
+		"#Fields: date time s-ip cs-method cs-uri-stem cs-uri-query s-port cs-username c-ip cs(User-Agent) cs(Referer) sc-status sc-substatus sc-win32-status time-taken",
+		"2023-12-12 14:00:01 10.0.0.5 GET /default.aspx id=1 443 - 203.0.113.7 Mozilla/5.0+(Windows+NT+10.0) https://example.com/ 404 3 50 15",
+		// The site was reconfigured to log fewer fields, without a date column
+		"#Fields: time c-ip cs-method cs-uri-stem sc-status cs(Cookie) sc(Content-Type)",
+		"14:00:02 203.0.113.8 POST /login 200 sid=abc text/html",
+	}
+
+	var got []*GenericLogEntry
+	for _, line := range lines {
+		if lp := p.Next(line); lp != nil {
+			entry, err := lp.Parse(line)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", line, err)
+			}
+			got = append(got, entry)
+		}
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d entries; want 2", len(got))
+	}
+
+	want := []GenericLogEntry{
+		{RemoteIP: "203.0.113.7", RemoteUser: "-", Method: "GET", Path: "/default.aspx?id=1", Status: 404, Referer: "https://example.com/", UserAgent: "Mozilla/5.0 (Windows NT 10.0)", Latency: 0.015,
+			Extra: Fields{"server_addr": "10.0.0.5", "server_port": "443", "substatus": "3", "win32_status": "50"}},
+		{RemoteIP: "203.0.113.8", RemoteUser: "-", Method: "POST", Path: "/login", Status: 200,
+			Extra: Fields{"http_cookie": "sid=abc", "sent_http_content_type": "text/html"}},
+	}
+	wantTimes := []time.Time{time.Date(2023, 12, 12, 14, 0, 1, 0, time.UTC), time.Date(2023, 12, 12, 14, 0, 2, 0, time.UTC)}
+	for i, entry := range got {
+		if !entry.TimeLocal.Equal(wantTimes[i]) {
+			t.Errorf("entry %d TimeLocal = %v; want %v", i, entry.TimeLocal, wantTimes[i])
+		}
+		entry.TimeLocal, entry.Service = time.Time{}, ""
+		if !reflect.DeepEqual(*entry, want[i]) {
+			t.Errorf("entry %d = %+v; want %+v", i, *entry, want[i])
+		}
+	}
+
+	if _, err := p.Parse("#Remark: rotated"); err == nil {
+		t.Error("Parse(directive) error = nil; want error")
+	}
+	if _, err := p.Parse("14:00:03 203.0.113.9 GET"); err == nil {
+		t.Error("Parse(short line) error = nil; want error")
+	}
+}
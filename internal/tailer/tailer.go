@@ -1,31 +1,71 @@
 package tailer
 
 import (
-	"github.com/nxadm/tail"
+	"io"
 	"log"
+
+	"github.com/nxadm/tail"
 )
 
-// TailFile tails a file and sends lines to the provided channel
-func TailFile(path string, lines chan<- string) {
+// Line is a complete line read from a tailed file
+type Line struct {
+	Text     string
+	Offset   int64 // Byte offset just past this line; restarts after rotation
+	Reopened bool  // First line after the file was reopened (rotated/truncated)
+}
+
+// Tailer follows a single file until stopped
+type Tailer struct {
+	Path string
+	t    *tail.Tail
+}
+
+// TailFile tails a file starting at byte offset from and sends lines to the
+// provided channel. The channel is closed once the tailer is stopped.
+// Returns nil if the file could not be tailed.
+func TailFile(path string, from int64, lines chan<- Line) *Tailer {
+	var location *tail.SeekInfo
+	if from > 0 {
+		location = &tail.SeekInfo{Offset: from, Whence: io.SeekStart}
+	}
+
 	t, err := tail.TailFile(path, tail.Config{
 		Follow: true,
 		ReOpen: true,
 		// If file doesn't exist, we still want to wait for it to appear
-		MustExist: false, 
+		MustExist: false,
 		Poll:      true, // Polling is often safer in Docker mounts
+		Location:  location,
+		// Hold back a trailing partial line so offsets always land on a line boundary
+		CompleteLines: true,
 	})
 	if err != nil {
 		log.Printf("Error tailing file %s: %v", path, err)
-		return
+		close(lines)
+		return nil
 	}
 
 	go func() {
+		defer close(lines)
+		lastNum := 0
 		for line := range t.Lines {
 			if line.Err != nil {
 				log.Printf("Error reading line from %s: %v", path, line.Err)
 				continue
 			}
-			lines <- line.Text
+			// Line numbers restart from 1 whenever the file is reopened
+			reopened := line.Num <= lastNum
+			lastNum = line.Num
+			lines <- Line{Text: line.Text, Offset: line.SeekInfo.Offset, Reopened: reopened}
 		}
 	}()
+
+	return &Tailer{Path: path, t: t}
+}
+
+// Stop ends tailing; the lines channel is closed once pending lines are drained.
+func (t *Tailer) Stop() {
+	if err := t.t.Stop(); err != nil {
+		log.Printf("Error stopping tailer for %s: %v", t.Path, err)
+	}
 }
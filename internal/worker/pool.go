@@ -1,7 +1,10 @@
 package worker
 
 import (
+	"errors"
 	"log"
+	"sync/atomic"
+	"time"
 
 	"log-sentry/internal/analyzer"
 	"log-sentry/internal/anomaly"
@@ -13,8 +16,10 @@ import (
 type Job struct {
 	ServiceName string
 	LogPath     string
+	Vhost       string // Captured from the log path, if configured
 	Line        string
 	Parser      parser.LogParser
+	Done        func() // Called once the line is processed, if set
 }
 
 type Pool struct {
@@ -24,6 +29,8 @@ type Pool struct {
 	Analyzer   *analyzer.Analyzer
 	AnomalyDetector *anomaly.AnomalyDetector
 	Enricher   *enricher.Enricher
+
+	pending atomic.Int64 // Submitted but not yet fully processed
 }
 
 func NewPool(workers int, coll *collector.LogCollector, analyzer *analyzer.Analyzer, ad *anomaly.AnomalyDetector, enrich *enricher.Enricher) *Pool {
@@ -46,42 +53,70 @@ func (p *Pool) Start() {
 
 func (p *Pool) worker(id int) {
 	for job := range p.JobQueue {
-		// 1. Parse
-		entry, err := job.Parser.Parse(job.Line)
-		if err != nil {
-			// Optional: log debug or count parse errors
-			continue
+		p.process(job)
+		if job.Done != nil {
+			job.Done()
 		}
-		
-		// Enforce service name from job context
-		entry.Service = job.ServiceName
+		p.pending.Add(-1)
+	}
+}
 
-		// 2. Security Analysis
-		attack := p.Analyzer.DetectAttack(entry.Path, entry.UserAgent)
-		if !attack.Detected {
-			// Check for data exfiltration if no other attack detected (or in addition?)
-			// Let's do in addition, but AttackResult is singular. Priority to Exfil?
-			// Or just overwrite if Exfil detected?
-			exfil := p.Analyzer.CheckDataExfiltration(entry.BodyBytesSent)
-			if exfil.Detected {
-				attack = exfil
-			}
+func (p *Pool) process(job Job) {
+	// 1. Parse
+	entry, err := job.Parser.Parse(job.Line)
+	if err != nil {
+		reason := "parse_failed"
+		if errors.Is(err, parser.ErrNoMatch) {
+			reason = "no_match"
 		}
-		
-		// 2b. Anomaly Detection
-		anomalyType := p.AnomalyDetector.Check(entry.RemoteIP, entry.Status)
-
-		// 2c. Enrichment
-		netType := p.Enricher.ClassifyIP(entry.RemoteIP)
-		// If entry.User exists, we could also resolve/enrich it here
-		// e.g. realUser := p.Enricher.ResolveUser(entry.User)
+		p.Collector.ParserErrors.WithLabelValues(job.ServiceName, reason).Inc()
+		return
+	}
+	
+	// Enforce service name from job context
+	entry.Service = job.ServiceName
 
-		// 3. Record Metrics
-		p.Collector.ProcessWeb(entry, attack, anomalyType, netType)
+	// 2. Security Analysis
+	attack := p.Analyzer.DetectAttack(entry.Path, entry.UserAgent)
+	if !attack.Detected {
+		// Check for data exfiltration if no other attack detected (or in addition?)
+		// Let's do in addition, but AttackResult is singular. Priority to Exfil?
+		// Or just overwrite if Exfil detected?
+		exfil := p.Analyzer.CheckDataExfiltration(entry.BodyBytesSent)
+		if exfil.Detected {
+			attack = exfil
+		}
 	}
+	
+	// 2b. Anomaly Detection (windowed on the entry's own timestamp)
+	anomalyType := p.AnomalyDetector.CheckAt(entry.RemoteIP, entry.Status, entry.TimeLocal)
+
+	// 2c. Enrichment
+	netType := p.Enricher.ClassifyIP(entry.RemoteIP)
+	// If entry.User exists, we could also resolve/enrich it here
+	// e.g. realUser := p.Enricher.ResolveUser(entry.User)
+
+	// 3. Record Metrics
+	p.Collector.ProcessWeb(entry, attack, anomalyType, netType)
+	p.Collector.ProcessSource(entry, job.LogPath, job.Vhost)
+	p.Collector.ProcessProxy(entry)
 }
 
 func (p *Pool) Submit(job Job) {
 	// Non-blocking try or blocking? blocking is safer for backpressure
+	p.pending.Add(1)
 	p.JobQueue <- job
 }
+
+// Drain waits until every submitted job has been processed, or timeout
+// elapses. Returns false on timeout.
+func (p *Pool) Drain(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for p.pending.Load() > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return true
+}